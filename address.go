@@ -0,0 +1,323 @@
+package virtualhere
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultHubPort is the TCP port VirtualHere servers listen on by default
+const DefaultHubPort = 7575
+
+// DeviceAddress identifies a USB device on a hub, e.g. "raspberrypi.114"
+type DeviceAddress struct {
+	Hub     string // Hostname of the hub the device is attached to, e.g. "raspberrypi"
+	Address int    // Numeric address of the device on the hub, e.g. 114
+}
+
+// ParseDeviceAddress parses a device address of the form "<hub>.<address>".
+// The hub part may itself contain dots; the address is the part after the last one.
+func ParseDeviceAddress(s string) (DeviceAddress, error) {
+	i := strings.LastIndex(s, ".")
+	if i <= 0 || i == len(s)-1 {
+		return DeviceAddress{}, fmt.Errorf("%w: %q is not of the form <hub>.<address>", ErrInvalidAddress, s)
+	}
+
+	hub, number := s[:i], s[i+1:]
+	if err := validateHost(hub); err != nil {
+		return DeviceAddress{}, fmt.Errorf("%w: %q: %v", ErrInvalidAddress, s, err)
+	}
+
+	address, err := strconv.Atoi(number)
+	if err != nil || address <= 0 {
+		return DeviceAddress{}, fmt.Errorf("%w: %q: device address %q is not a positive number", ErrInvalidAddress, s, number)
+	}
+
+	return DeviceAddress{Hub: hub, Address: address}, nil
+}
+
+// String returns the address in the form used by the VirtualHere client
+func (a DeviceAddress) String() string {
+	if a.IsZero() {
+		return ""
+	}
+	return a.Hub + "." + strconv.Itoa(a.Address)
+}
+
+// IsZero reports whether a is the zero DeviceAddress
+func (a DeviceAddress) IsZero() bool {
+	return a == DeviceAddress{}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (a DeviceAddress) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (a *DeviceAddress) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*a = DeviceAddress{}
+		return nil
+	}
+
+	parsed, err := ParseDeviceAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// HubAddress identifies a VirtualHere server (hub), either by host and
+// optional port, e.g. "raspberrypi:7575" or "raspberrypi", or by EasyFind
+// address, e.g. "a1b2c3d4e5f6.easyfind"
+type HubAddress struct {
+	Host     string // Hostname or IP address, or the EasyFind address itself
+	Port     int    // TCP port; 0 if not given, in which case the client uses DefaultHubPort
+	EasyFind bool   // Whether Host is an EasyFind address
+}
+
+// ParseHubAddress parses "host[:port]", with IPv6 hosts in brackets. A host with an "easyfind" domain label and no port, such as
+// "a1b2c3d4e5f6.easyfind", is an EasyFind address.
+func ParseHubAddress(s string) (HubAddress, error) {
+	if s == "" {
+		return HubAddress{}, fmt.Errorf("%w: empty hub address", ErrInvalidAddress)
+	}
+
+	host, portText, err := net.SplitHostPort(s)
+	if err != nil {
+		// No port: a bare host or an EasyFind address. IPv6 hosts need
+		// brackets and a port, or the client would take a colon for the port.
+		if strings.Contains(s, ":") {
+			return HubAddress{}, fmt.Errorf("%w: %q: %v", ErrInvalidAddress, s, err)
+		}
+		if err := validateHost(s); err != nil {
+			return HubAddress{}, fmt.Errorf("%w: %q: %v", ErrInvalidAddress, s, err)
+		}
+		return HubAddress{Host: s, EasyFind: isEasyFindHost(s)}, nil
+	}
+
+	if err := validateHost(host); err != nil {
+		return HubAddress{}, fmt.Errorf("%w: %q: %v", ErrInvalidAddress, s, err)
+	}
+
+	port, err := strconv.Atoi(portText)
+	if err != nil || port < 1 || port > 65535 {
+		return HubAddress{}, fmt.Errorf("%w: %q: port %q is not in the range 1-65535", ErrInvalidAddress, s, portText)
+	}
+
+	return HubAddress{Host: host, Port: port}, nil
+}
+
+// isEasyFindHost reports whether host has an "easyfind" domain label, as
+// EasyFind addresses do
+func isEasyFindHost(host string) bool {
+	labels := strings.Split(strings.ToLower(host), ".")
+	return len(labels) > 1 && slices.Contains(labels[1:], "easyfind")
+}
+
+// NewHubAddress returns the address of a hub at host on the given port
+func NewHubAddress(host string, port int) HubAddress {
+	return HubAddress{Host: host, Port: port}
+}
+
+// String returns the address in the form used by the VirtualHere client
+func (a HubAddress) String() string {
+	if a.EasyFind || a.Port == 0 {
+		return a.Host
+	}
+	return net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+}
+
+// IsZero reports whether a is the zero HubAddress
+func (a HubAddress) IsZero() bool {
+	return a == HubAddress{}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (a HubAddress) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (a *HubAddress) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*a = HubAddress{}
+		return nil
+	}
+
+	parsed, err := ParseHubAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// validateHost rejects empty hosts and characters that would corrupt a command
+func validateHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("empty host")
+	}
+	if strings.ContainsAny(host, ", \t\r\n") {
+		return fmt.Errorf("host %q contains a separator or whitespace", host)
+	}
+	return nil
+}
+
+// ParsedAddress parses the device's address
+func (d Device) ParsedAddress() (DeviceAddress, error) {
+	return ParseDeviceAddress(d.Address)
+}
+
+// ParsedAddress parses the hub's address
+func (h Hub) ParsedAddress() (HubAddress, error) {
+	return ParseHubAddress(h.Address)
+}
+
+// UseAt is like Use but takes a typed device address
+func (c *Client) UseAt(address DeviceAddress, password string) error {
+	return c.UseAtContext(context.Background(), address, password)
+}
+
+// UseAtContext is like UseAt but uses ctx to cancel or time out the command
+func (c *Client) UseAtContext(ctx context.Context, address DeviceAddress, password string) error {
+	if address.IsZero() {
+		return fmt.Errorf("%w: empty device address", ErrInvalidAddress)
+	}
+	return c.UseContext(ctx, address.String(), password)
+}
+
+// StopUsingAt is like StopUsing but takes a typed device address
+func (c *Client) StopUsingAt(address DeviceAddress) error {
+	return c.StopUsingAtContext(context.Background(), address)
+}
+
+// StopUsingAtContext is like StopUsingAt but uses ctx to cancel or time out the command
+func (c *Client) StopUsingAtContext(ctx context.Context, address DeviceAddress) error {
+	if address.IsZero() {
+		return fmt.Errorf("%w: empty device address", ErrInvalidAddress)
+	}
+	return c.StopUsingContext(ctx, address.String())
+}
+
+// ManualHubAddAt is like ManualHubAdd but takes a typed hub address
+func (c *Client) ManualHubAddAt(address HubAddress) error {
+	return c.ManualHubAddAtContext(context.Background(), address)
+}
+
+// ManualHubAddAtContext is like ManualHubAddAt but uses ctx to cancel or time out the command
+func (c *Client) ManualHubAddAtContext(ctx context.Context, address HubAddress) error {
+	if address.IsZero() {
+		return fmt.Errorf("%w: empty hub address", ErrInvalidAddress)
+	}
+	return c.ManualHubAddContext(ctx, address.String())
+}
+
+// ManualHubRemoveAt is like ManualHubRemove but takes a typed hub address
+func (c *Client) ManualHubRemoveAt(address HubAddress) error {
+	return c.ManualHubRemoveAtContext(context.Background(), address)
+}
+
+// ManualHubRemoveAtContext is like ManualHubRemoveAt but uses ctx to cancel or time out the command
+func (c *Client) ManualHubRemoveAtContext(ctx context.Context, address HubAddress) error {
+	if address.IsZero() {
+		return fmt.Errorf("%w: empty hub address", ErrInvalidAddress)
+	}
+	return c.ManualHubRemoveContext(ctx, address.String())
+}
+
+// AutoUsePortAt is like AutoUsePort but takes a typed device address
+func (c *Client) AutoUsePortAt(address DeviceAddress) error {
+	return c.AutoUsePortAtContext(context.Background(), address)
+}
+
+// AutoUsePortAtContext is like AutoUsePortAt but uses ctx to cancel or time out the command
+func (c *Client) AutoUsePortAtContext(ctx context.Context, address DeviceAddress) error {
+	if address.IsZero() {
+		return fmt.Errorf("%w: empty device address", ErrInvalidAddress)
+	}
+	return c.AutoUsePortContext(ctx, address.String())
+}
+
+// AutoUseDeviceAt is like AutoUseDevice but takes a typed device address
+func (c *Client) AutoUseDeviceAt(address DeviceAddress) error {
+	return c.AutoUseDeviceAtContext(context.Background(), address)
+}
+
+// AutoUseDeviceAtContext is like AutoUseDeviceAt but uses ctx to cancel or time out the command
+func (c *Client) AutoUseDeviceAtContext(ctx context.Context, address DeviceAddress) error {
+	if address.IsZero() {
+		return fmt.Errorf("%w: empty device address", ErrInvalidAddress)
+	}
+	return c.AutoUseDeviceContext(ctx, address.String())
+}
+
+// AutoUseDevicePortAt is like AutoUseDevicePort but takes a typed device address
+func (c *Client) AutoUseDevicePortAt(address DeviceAddress) error {
+	return c.AutoUseDevicePortAtContext(context.Background(), address)
+}
+
+// AutoUseDevicePortAtContext is like AutoUseDevicePortAt but uses ctx to cancel or time out the command
+func (c *Client) AutoUseDevicePortAtContext(ctx context.Context, address DeviceAddress) error {
+	if address.IsZero() {
+		return fmt.Errorf("%w: empty device address", ErrInvalidAddress)
+	}
+	return c.AutoUseDevicePortContext(ctx, address.String())
+}
+
+// DeviceInfoAt is like DeviceInfo but takes a typed device address
+func (c *Client) DeviceInfoAt(address DeviceAddress) (*DeviceInfo, error) {
+	return c.DeviceInfoAtContext(context.Background(), address)
+}
+
+// DeviceInfoAtContext is like DeviceInfoAt but uses ctx to cancel or time out the command
+func (c *Client) DeviceInfoAtContext(ctx context.Context, address DeviceAddress) (*DeviceInfo, error) {
+	if address.IsZero() {
+		return nil, fmt.Errorf("%w: empty device address", ErrInvalidAddress)
+	}
+	return c.DeviceInfoContext(ctx, address.String())
+}
+
+// DeviceRenameAt is like DeviceRename but takes a typed device address
+func (c *Client) DeviceRenameAt(address DeviceAddress, nickname string) error {
+	return c.DeviceRenameAtContext(context.Background(), address, nickname)
+}
+
+// DeviceRenameAtContext is like DeviceRenameAt but uses ctx to cancel or time out the command
+func (c *Client) DeviceRenameAtContext(ctx context.Context, address DeviceAddress, nickname string) error {
+	if address.IsZero() {
+		return fmt.Errorf("%w: empty device address", ErrInvalidAddress)
+	}
+	return c.DeviceRenameContext(ctx, address.String(), nickname)
+}
+
+// StopUsingAllAt is like StopUsingAll but takes a typed hub address
+func (c *Client) StopUsingAllAt(address HubAddress) error {
+	return c.StopUsingAllAtContext(context.Background(), address)
+}
+
+// StopUsingAllAtContext is like StopUsingAllAt but uses ctx to cancel or time out the command
+func (c *Client) StopUsingAllAtContext(ctx context.Context, address HubAddress) error {
+	if address.IsZero() {
+		return fmt.Errorf("%w: empty hub address", ErrInvalidAddress)
+	}
+	return c.StopUsingAllContext(ctx, address.String())
+}
+
+// ServerInfoAt is like ServerInfo but takes a typed hub address
+func (c *Client) ServerInfoAt(address HubAddress) (*ServerInfo, error) {
+	return c.ServerInfoAtContext(context.Background(), address)
+}
+
+// ServerInfoAtContext is like ServerInfoAt but uses ctx to cancel or time out the command
+func (c *Client) ServerInfoAtContext(ctx context.Context, address HubAddress) (*ServerInfo, error) {
+	if address.IsZero() {
+		return nil, fmt.Errorf("%w: empty hub address", ErrInvalidAddress)
+	}
+	return c.ServerInfoContext(ctx, address.String())
+}
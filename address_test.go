@@ -0,0 +1,162 @@
+package virtualhere
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseDeviceAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want DeviceAddress
+	}{
+		{"raspberrypi.114", DeviceAddress{Hub: "raspberrypi", Address: 114}},
+		{"lab.example.com.12", DeviceAddress{Hub: "lab.example.com", Address: 12}},
+		{"192.168.1.10.3", DeviceAddress{Hub: "192.168.1.10", Address: 3}},
+	}
+	for _, tt := range tests {
+		got, err := ParseDeviceAddress(tt.in)
+		if err != nil {
+			t.Errorf("ParseDeviceAddress(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDeviceAddress(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if got.String() != tt.in {
+			t.Errorf("ParseDeviceAddress(%q).String() = %q", tt.in, got.String())
+		}
+
+		var decoded DeviceAddress
+		text, _ := got.MarshalText()
+		if err := decoded.UnmarshalText(text); err != nil || decoded != got {
+			t.Errorf("text round trip of %q gave %+v, %v", tt.in, decoded, err)
+		}
+	}
+
+	for _, in := range []string{"", "raspberrypi", ".114", "raspberrypi.", "raspberrypi.0", "raspberrypi.-1", "raspberrypi.x", "rasp,berrypi.1", "rasp berrypi.1"} {
+		if _, err := ParseDeviceAddress(in); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("ParseDeviceAddress(%q) returned %v, want ErrInvalidAddress", in, err)
+		}
+	}
+}
+
+func TestParseHubAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want HubAddress
+	}{
+		{"raspberrypi:7575", HubAddress{Host: "raspberrypi", Port: 7575}},
+		{"192.168.1.100:7575", HubAddress{Host: "192.168.1.100", Port: 7575}},
+		{"[fe80::1]:7575", HubAddress{Host: "fe80::1", Port: 7575}},
+		{"[::1]:1", HubAddress{Host: "::1", Port: 1}},
+		{"lab.example.com:65535", HubAddress{Host: "lab.example.com", Port: 65535}},
+		{"a1b2c3d4e5f6.easyfind", HubAddress{Host: "a1b2c3d4e5f6.easyfind", EasyFind: true}},
+		{"myserver.EasyFind.com", HubAddress{Host: "myserver.EasyFind.com", EasyFind: true}},
+		{"raspberrypi", HubAddress{Host: "raspberrypi"}},
+		{"192.168.1.10", HubAddress{Host: "192.168.1.10"}},
+		{"easyfind.example.com", HubAddress{Host: "easyfind.example.com"}},
+		{"lab.easyfind:7575", HubAddress{Host: "lab.easyfind", Port: 7575}},
+	}
+	for _, tt := range tests {
+		got, err := ParseHubAddress(tt.in)
+		if err != nil {
+			t.Errorf("ParseHubAddress(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseHubAddress(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if got.String() != tt.in {
+			t.Errorf("ParseHubAddress(%q).String() = %q", tt.in, got.String())
+		}
+
+		var decoded HubAddress
+		text, _ := got.MarshalText()
+		if err := decoded.UnmarshalText(text); err != nil || decoded != got {
+			t.Errorf("text round trip of %q gave %+v, %v", tt.in, decoded, err)
+		}
+	}
+
+	for _, in := range []string{"", "raspberrypi:0", "raspberrypi:65536", "raspberrypi:-1", "raspberrypi:http", ":7575", "fe80::1", "[fe80::1]", "rasp,berrypi:7575", "rasp berrypi"} {
+		if _, err := ParseHubAddress(in); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("ParseHubAddress(%q) returned %v, want ErrInvalidAddress", in, err)
+		}
+	}
+}
+
+func TestNewHubAddress(t *testing.T) {
+	if got := NewHubAddress("fe80::1", DefaultHubPort).String(); got != "[fe80::1]:7575" {
+		t.Errorf("NewHubAddress(fe80::1).String() = %q", got)
+	}
+}
+
+func TestTypedAddressCommands(t *testing.T) {
+	var sent []string
+	client, err := NewClientWithTransport(TransportFunc(func(ctx context.Context, command string) (string, error) {
+		sent = append(sent, command)
+		return "OK", nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	device := DeviceAddress{Hub: "lab.example.com", Address: 12}
+	hub := HubAddress{Host: "fe80::1", Port: 7575}
+	calls := []struct {
+		call func() error
+		want string
+	}{
+		{func() error { return client.UseAt(device, "") }, "USE,lab.example.com.12"},
+		{func() error { return client.StopUsingAt(device) }, "STOP USING,lab.example.com.12"},
+		{func() error { return client.AutoUsePortAt(device) }, "AUTO USE PORT,lab.example.com.12"},
+		{func() error { return client.AutoUseDeviceAt(device) }, "AUTO USE DEVICE,lab.example.com.12"},
+		{func() error { return client.AutoUseDevicePortAt(device) }, "AUTO USE DEVICE PORT,lab.example.com.12"},
+		{func() error { _, err := client.DeviceInfoAt(device); return err }, "DEVICE INFO,lab.example.com.12"},
+		{func() error { return client.DeviceRenameAt(device, "jtag") }, "DEVICE RENAME,lab.example.com.12,jtag"},
+		{func() error { return client.StopUsingAllAt(hub) }, "STOP USING ALL,[fe80::1]:7575"},
+		{func() error { _, err := client.ServerInfoAt(hub); return err }, "SERVER INFO,[fe80::1]:7575"},
+		{func() error { return client.ManualHubAddAt(hub) }, "MANUAL HUB ADD,[fe80::1]:7575"},
+		{func() error { return client.ManualHubRemoveAt(hub) }, "MANUAL HUB REMOVE,[fe80::1]:7575"},
+	}
+	for _, c := range calls {
+		sent = nil
+		if err := c.call(); err != nil {
+			t.Errorf("%s: %v", c.want, err)
+		}
+		if len(sent) != 1 || sent[0] != c.want {
+			t.Errorf("sent %q, want %q", sent, c.want)
+		}
+	}
+
+	sent = nil
+	if _, err := client.DeviceInfoAt(DeviceAddress{}); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("DeviceInfoAt(zero) returned %v, want ErrInvalidAddress", err)
+	}
+	if err := client.StopUsingAllAt(HubAddress{}); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("StopUsingAllAt(zero) returned %v, want ErrInvalidAddress", err)
+	}
+	if len(sent) != 0 {
+		t.Errorf("zero addresses sent %q", sent)
+	}
+}
+
+func TestHubRefAddress(t *testing.T) {
+	tests := []struct {
+		host string
+		port int
+		want HubAddress
+	}{
+		{"raspberrypi", 7575, HubAddress{Host: "raspberrypi", Port: 7575}},
+		{"192.168.1.10", 0, HubAddress{Host: "192.168.1.10"}},
+		{"a1b2c3d4e5f6.easyfind", 0, HubAddress{Host: "a1b2c3d4e5f6.easyfind", EasyFind: true}},
+		{"", 0, HubAddress{}},
+	}
+	for _, tt := range tests {
+		server := XMLServer{Connection: XMLServerConnection{Host: tt.host, Port: tt.port}}
+		if got := server.hubRef().Address; got != tt.want {
+			t.Errorf("hub reference of %s port %d has address %+v, want %+v", tt.host, tt.port, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package virtualhere
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CommandUsage is one command listed by HELP
+type CommandUsage struct {
+	Verb  string `json:"verb"`  // e.g. "DEVICE RENAME"
+	Usage string `json:"usage"` // Line as listed, e.g. "DEVICE RENAME,<address>,<nickname>"
+}
+
+// Capabilities is the catalog of commands a running VirtualHere client
+// supports, as listed by its HELP command
+type Capabilities struct {
+	Commands []CommandUsage `json:"commands"`
+}
+
+// ParseHelp parses the output of HELP into a command catalog. Command lines
+// may be bulleted with "-" or "*" and followed by a description, as in
+// " - USE,<address>[,password] - use a device". Lines that do not start with
+// an upper-case verb, such as the header, are skipped.
+func ParseHelp(output string) *Capabilities {
+	caps := &Capabilities{Commands: make([]CommandUsage, 0)}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*"))
+		verb := helpVerb(line)
+		if verb == "" || caps.Supports(verb) {
+			continue
+		}
+		caps.Commands = append(caps.Commands, CommandUsage{Verb: verb, Usage: line})
+	}
+	return caps
+}
+
+// helpVerb returns the verb a HELP line starts with. The verb ends at an
+// argument list, a description separated by " - ", a tab or several spaces,
+// or the line end, and must consist of upper-case words. Other lines, such as
+// "VirtualHere Client IPC, available commands:", are prose and yield "".
+func helpVerb(line string) string {
+	verb := line
+	if end := strings.IndexAny(verb, ",([<\t"); end >= 0 {
+		verb = verb[:end]
+	}
+	for _, separator := range []string{" - ", "  "} {
+		verb, _, _ = strings.Cut(verb, separator)
+	}
+	verb = strings.TrimSpace(verb)
+
+	for _, word := range strings.Split(verb, " ") {
+		if word == "" || strings.TrimLeft(word, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return ""
+		}
+	}
+	return verb
+}
+
+// Supports reports whether verb is listed
+func (c *Capabilities) Supports(verb string) bool {
+	return slices.ContainsFunc(c.Commands, func(u CommandUsage) bool {
+		return u.Verb == verb
+	})
+}
+
+// Unsupported returns the registered verbs the client does not list
+func (c *Capabilities) Unsupported() []string {
+	var verbs []string
+	for _, spec := range commandSpecs {
+		if !c.Supports(spec.Verb) {
+			verbs = append(verbs, spec.Verb)
+		}
+	}
+	return verbs
+}
+
+// WithCapabilityDiscovery makes the client send HELP before its first command
+// and reject commands the running client does not list with ErrUnsupported.
+// Without it, commands are only checked once Capabilities has been called.
+func WithCapabilityDiscovery(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.discoverCapabilities = enabled
+	}
+}
+
+// discoveryBackoff spaces out HELP after failed discoveries; the delay grows
+// with each consecutive failure
+var discoveryBackoff = RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Minute, Multiplier: 2}
+
+// capsCall is a HELP exchange in progress, shared by every caller that needs
+// the capabilities meanwhile
+type capsCall struct {
+	done      chan struct{} // Closed once caps and err are set
+	caps      *Capabilities
+	err       error
+	abandoned bool // The caller sending HELP gave up, so err says nothing about the client
+}
+
+// Capabilities returns the commands supported by the running VirtualHere
+// client, parsed from HELP. The result is cached, and from then on commands
+// the client does not list fail with ErrUnsupported without being sent.
+// Concurrent callers share a single HELP. A failure is cached too: until a
+// backoff growing with each consecutive failure has passed, the error is
+// returned without sending HELP again.
+func (c *Client) Capabilities() (*Capabilities, error) {
+	return c.CapabilitiesContext(context.Background())
+}
+
+// CapabilitiesContext is like Capabilities but uses ctx to cancel or time out the command
+func (c *Client) CapabilitiesContext(ctx context.Context) (*Capabilities, error) {
+	for {
+		c.capsMu.Lock()
+		if c.caps != nil {
+			caps := c.caps
+			c.capsMu.Unlock()
+			return caps, nil
+		}
+		if c.capsErr != nil && time.Now().Before(c.capsRetryAt) {
+			err := c.capsErr
+			c.capsMu.Unlock()
+			return nil, err
+		}
+
+		if call := c.capsCall; call != nil {
+			c.capsMu.Unlock()
+			select {
+			case <-call.done:
+				if call.abandoned {
+					// Send HELP again under ctx, which is still live
+					continue
+				}
+				return call.caps, call.err
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		call := &capsCall{done: make(chan struct{})}
+		c.capsCall = call
+		c.capsMu.Unlock()
+
+		call.caps, call.err = c.fetchCapabilities(ctx)
+		call.abandoned = call.err != nil && ctx.Err() != nil
+
+		c.capsMu.Lock()
+		c.capsCall = nil
+		switch {
+		case call.err == nil:
+			c.caps, c.capsErr, c.capsFailures = call.caps, nil, 0
+		case !call.abandoned:
+			c.capsFailures++
+			c.capsErr = call.err
+			c.capsRetryAt = time.Now().Add(discoveryBackoff.backoff(c.capsFailures))
+		}
+		c.capsMu.Unlock()
+		close(call.done)
+
+		return call.caps, call.err
+	}
+}
+
+// fetchCapabilities sends HELP and parses its output
+func (c *Client) fetchCapabilities(ctx context.Context) (*Capabilities, error) {
+	result, err := c.execute(ctx, "HELP")
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, result.Error
+	}
+
+	caps := ParseHelp(result.Output)
+	if len(caps.Commands) == 0 {
+		return nil, fmt.Errorf("%w: HELP listed no commands", ErrInvalidResponse)
+	}
+	return caps, nil
+}
+
+// ResetCapabilities drops the cached capabilities, or the cached failure to
+// discover them, e.g. after the VirtualHere client was upgraded. They are
+// fetched again when next needed.
+func (c *Client) ResetCapabilities() {
+	c.capsMu.Lock()
+	c.caps, c.capsErr, c.capsFailures = nil, nil, 0
+	c.capsMu.Unlock()
+}
+
+// checkSupported fails with ErrUnsupported if the capabilities of the client
+// are known and do not list cmd. HELP itself is always allowed.
+func (c *Client) checkSupported(ctx context.Context, cmd Command) error {
+	if cmd.Verb == "HELP" {
+		return nil
+	}
+
+	var caps *Capabilities
+	if c.discoverCapabilities {
+		// A failed discovery is not fatal: the command is sent unchecked,
+		// and discovery is attempted again once its backoff has passed
+		caps, _ = c.CapabilitiesContext(ctx)
+	} else {
+		c.capsMu.Lock()
+		caps = c.caps
+		c.capsMu.Unlock()
+	}
+
+	if caps != nil && !caps.Supports(cmd.Verb) {
+		return fmt.Errorf("%w: %s", ErrUnsupported, cmd.Verb)
+	}
+	return nil
+}
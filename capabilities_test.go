@@ -0,0 +1,325 @@
+package virtualhere
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// realHelp is HELP output in the format of the VirtualHere client, as also
+// served by vhtest: a prose header, bulleted commands and descriptions after
+// " - "
+const realHelp = `VirtualHere Client IPC, below are the available commands (press Q to quit):
+
+ - LIST - list the devices of all servers
+ - GET CLIENT STATE - detailed state as XML
+ - USE,<address>[,password] - use a device
+ - STOP USING,<address>
+ - STOP USING ALL[,<server address>]
+ - STOP USING ALL LOCAL
+ - DEVICE INFO,<address>
+ - SERVER INFO,<server name>
+ - DEVICE RENAME,<address>,<nickname>
+ - SERVER RENAME,<hub address:port>,<name>
+ - AUTO USE ALL - toggle auto-use of all devices
+ - AUTO USE HUB,<server name>
+ - AUTO USE PORT,<address>
+ - AUTO USE DEVICE,<address>
+ - AUTO USE DEVICE PORT,<address>
+ - AUTO USE CLEAR ALL
+ - MANUAL HUB ADD,<host or IP address>[:port] | <EasyFind address>
+ - MANUAL HUB REMOVE,<host or IP address>[:port] | <EasyFind address>
+ - MANUAL HUB REMOVE ALL
+ - MANUAL HUB LIST
+ - ADD REVERSE,<server serial>,<client address>
+ - REMOVE REVERSE,<server serial>,<client address>
+ - LIST REVERSE,<server serial>
+ - LIST LICENSES
+ - LICENSE SERVER,<license key>
+ - CLEAR LOG
+ - CUSTOM EVENT,<address>,<event>
+ * AUTOFIND - toggle Auto-Find
+ * REVERSE - toggle Reverse Lookup
+ * SSLREVERSE - toggle Reverse SSL Lookup
+ - HELP
+ - EXIT
+
+Devices are addressed as <server>.<address>, as shown by LIST.
+`
+
+func TestHelpVerb(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"LIST", "LIST"},
+		{"LIST - list the devices of all servers", "LIST"},
+		{"GET CLIENT STATE - detailed state as XML", "GET CLIENT STATE"},
+		{"USE,<address>[,password] - use a device", "USE"},
+		{"STOP USING ALL[,<server address>]", "STOP USING ALL"},
+		{"MANUAL HUB ADD,<host or IP address>[:port] | <EasyFind address>", "MANUAL HUB ADD"},
+		{"AUTO USE ALL  Toggle auto-use of all devices", "AUTO USE ALL"},
+		{"AUTOFIND\tToggle Auto-Find", "AUTOFIND"},
+		{"EXIT (quit)", "EXIT"},
+		{"VirtualHere Client IPC, below are the available commands (press Q to quit):", ""},
+		{"Devices are addressed as <server>.<address>, as shown by LIST.", ""},
+		{"USB devices", ""},
+		{"COMMANDS:", ""},
+		{"(press Q to quit)", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := helpVerb(tt.line); got != tt.want {
+			t.Errorf("helpVerb(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseHelp(t *testing.T) {
+	caps := ParseHelp(realHelp)
+
+	if unsupported := caps.Unsupported(); len(unsupported) != 0 {
+		t.Errorf("Unsupported() = %v, want none", unsupported)
+	}
+	if len(caps.Commands) != len(commandSpecs) {
+		t.Errorf("parsed %d commands, want %d", len(caps.Commands), len(commandSpecs))
+	}
+
+	wantUsage := map[string]string{
+		"USE":            "USE,<address>[,password] - use a device",
+		"AUTOFIND":       "AUTOFIND - toggle Auto-Find",
+		"MANUAL HUB ADD": "MANUAL HUB ADD,<host or IP address>[:port] | <EasyFind address>",
+	}
+	for _, usage := range caps.Commands {
+		if want, ok := wantUsage[usage.Verb]; ok && usage.Usage != want {
+			t.Errorf("usage of %s = %q, want %q", usage.Verb, usage.Usage, want)
+		}
+	}
+}
+
+func TestParseHelpOlderClient(t *testing.T) {
+	caps := ParseHelp("VirtualHere Client IPC, available commands:\nLIST\nUSE,<address>\n* STOP USING,<address>\n")
+
+	want := []CommandUsage{
+		{Verb: "LIST", Usage: "LIST"},
+		{Verb: "USE", Usage: "USE,<address>"},
+		{Verb: "STOP USING", Usage: "STOP USING,<address>"},
+	}
+	if !reflect.DeepEqual(caps.Commands, want) {
+		t.Errorf("Commands = %+v, want %+v", caps.Commands, want)
+	}
+	if !caps.Supports("STOP USING") || caps.Supports("STOP USING ALL") {
+		t.Error("Supports must match whole verbs")
+	}
+	if unsupported := caps.Unsupported(); len(unsupported) != len(commandSpecs)-3 {
+		t.Errorf("Unsupported() lists %d verbs, want %d", len(unsupported), len(commandSpecs)-3)
+	}
+}
+
+// helpTransport answers HELP with help and every other command with OK,
+// recording the commands it receives
+func helpTransport(help string) (Transport, *[]string) {
+	var commands []string
+	return TransportFunc(func(ctx context.Context, command string) (string, error) {
+		commands = append(commands, command)
+		if command == "HELP" {
+			return help, nil
+		}
+		return "OK", nil
+	}), &commands
+}
+
+func TestCapabilityDiscovery(t *testing.T) {
+	transport, commands := helpTransport(realHelp)
+	client, err := NewClientWithTransport(transport, WithCapabilityDiscovery(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.ClearLog(); err != nil {
+		t.Fatalf("CLEAR LOG: %v", err)
+	}
+	if err := client.AutoFind(); err != nil {
+		t.Fatalf("AUTOFIND: %v", err)
+	}
+	if want := []string{"HELP", "CLEAR LOG", "AUTOFIND"}; !reflect.DeepEqual(*commands, want) {
+		t.Errorf("sent %q, want %q", *commands, want)
+	}
+}
+
+func TestCapabilityDiscoveryRejectsUnlisted(t *testing.T) {
+	transport, commands := helpTransport("VirtualHere Client IPC, available commands:\n - LIST - list devices\n - HELP\n")
+	client, err := NewClientWithTransport(transport, WithCapabilityDiscovery(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.ClearLog()
+	if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "CLEAR LOG") {
+		t.Errorf("CLEAR LOG returned %v, want ErrUnsupported", err)
+	}
+	if want := []string{"HELP"}; !reflect.DeepEqual(*commands, want) {
+		t.Errorf("sent %q, want %q", *commands, want)
+	}
+}
+
+// countingTransport passes every command to handle and counts the HELPs sent.
+// It is safe for concurrent use.
+type countingTransport struct {
+	mu     sync.Mutex
+	helps  int
+	handle func(ctx context.Context, command string, help int) (string, error)
+}
+
+func (t *countingTransport) Send(ctx context.Context, command string) (string, error) {
+	t.mu.Lock()
+	if command == "HELP" {
+		t.helps++
+	}
+	help := t.helps
+	t.mu.Unlock()
+	return t.handle(ctx, command, help)
+}
+
+func (t *countingTransport) Helps() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.helps
+}
+
+func TestCapabilityDiscoveryConcurrent(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	transport := &countingTransport{handle: func(ctx context.Context, command string, help int) (string, error) {
+		if command == "HELP" {
+			close(started)
+			<-release
+			return realHelp, nil
+		}
+		return "OK", nil
+	}}
+	client, err := NewClientWithTransport(transport, WithCapabilityDiscovery(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const callers = 8
+	done := make(chan error)
+	for range callers {
+		go func() { done <- client.ClearLog() }()
+	}
+	<-started
+
+	// A caller waiting for the HELP in progress gives up at its own deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.CapabilitiesContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiting for HELP past the deadline returned %v", err)
+	}
+
+	close(release)
+	for range callers {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := transport.Helps(); n != 1 {
+		t.Errorf("sent HELP %d times, want 1", n)
+	}
+}
+
+func TestCapabilityDiscoveryCachesFailure(t *testing.T) {
+	saved := discoveryBackoff
+	t.Cleanup(func() { discoveryBackoff = saved })
+	discoveryBackoff = RetryPolicy{InitialBackoff: time.Hour}
+
+	transport := &countingTransport{handle: func(ctx context.Context, command string, help int) (string, error) {
+		if command == "HELP" {
+			return "", syscall.ECONNREFUSED
+		}
+		return "OK", nil
+	}}
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	client, err := NewClientWithTransport(transport, WithCapabilityDiscovery(true), WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commands go out unchecked, and HELP is retried by the first one only
+	for range 3 {
+		if err := client.ClearLog(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := transport.Helps(); n != policy.MaxAttempts {
+		t.Errorf("sent HELP %d times, want %d", n, policy.MaxAttempts)
+	}
+	if _, err := client.Capabilities(); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("Capabilities() returned %v, want the cached failure", err)
+	}
+	if n := transport.Helps(); n != policy.MaxAttempts {
+		t.Errorf("Capabilities() sent HELP during the backoff")
+	}
+
+	// Once the backoff has passed, or the cache was reset, HELP is sent again
+	discoveryBackoff = RetryPolicy{InitialBackoff: time.Millisecond}
+	client.ResetCapabilities()
+	client.ClearLog()
+	if n := transport.Helps(); n != 2*policy.MaxAttempts {
+		t.Errorf("sent HELP %d times after ResetCapabilities, want %d", n, 2*policy.MaxAttempts)
+	}
+	time.Sleep(10 * time.Millisecond)
+	client.ClearLog()
+	if n := transport.Helps(); n != 3*policy.MaxAttempts {
+		t.Errorf("sent HELP %d times after the backoff, want %d", n, 3*policy.MaxAttempts)
+	}
+}
+
+func TestCapabilityDiscoveryAbandoned(t *testing.T) {
+	started := make(chan struct{})
+	transport := &countingTransport{handle: func(ctx context.Context, command string, help int) (string, error) {
+		if help == 1 {
+			// The first HELP hangs until its caller gives up
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return realHelp, nil
+	}}
+	client, err := NewClientWithTransport(transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	abandoned := make(chan error)
+	go func() {
+		_, err := client.CapabilitiesContext(ctx)
+		abandoned <- err
+	}()
+	<-started
+
+	waiter := make(chan error)
+	go func() {
+		_, err := client.Capabilities()
+		waiter <- err
+	}()
+	cancel()
+
+	if err := <-abandoned; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller got %v", err)
+	}
+	// The cancellation says nothing about the client, so it is not cached
+	// and a caller still waiting sends HELP itself
+	if err := <-waiter; err != nil {
+		t.Errorf("waiting caller got %v", err)
+	}
+	if n := transport.Helps(); n != 2 {
+		t.Errorf("sent HELP %d times, want 2", n)
+	}
+}
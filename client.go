@@ -1,15 +1,21 @@
 package virtualhere
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 )
 
-// Client represents a VirtualHere USB client controller
+// Client represents a VirtualHere USB client controller.
+// A Client is safe for concurrent use by multiple goroutines: commands are
+// queued and exchanged with the VirtualHere client one at a time, so each
+// caller receives the response to its own command. See WithPriority.
 type Client struct {
 	binaryPath           string
 	serviceCmd           *exec.Cmd
@@ -18,6 +24,22 @@ type Client struct {
 	onProcessTerminated  func()
 	processMonitorDone   chan struct{}
 	processMonitorCancel chan struct{}
+	logger               *slog.Logger
+	logLevels            *LogLevels
+	metrics              Metrics
+	transport            Transport
+	requestPath          string
+	responsePath         string
+	queue                commandQueue
+	retry                RetryPolicy
+	strict               bool
+	discoverCapabilities bool
+	capsMu               sync.Mutex
+	caps                 *Capabilities
+	capsCall             *capsCall // HELP in progress, if any
+	capsErr              error     // Last discovery failure, returned until capsRetryAt
+	capsRetryAt          time.Time
+	capsFailures         int // Consecutive discovery failures
 }
 
 // ClientOption is a function that configures a Client
@@ -40,6 +62,14 @@ func WithOnProcessTerminated(callback func()) ClientOption {
 	}
 }
 
+// WithTransport sets the Transport used to deliver commands, replacing the
+// platform default. Use it to plug in custom IPC, test doubles or wrappers.
+func WithTransport(transport Transport) ClientOption {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
 // NewPipeClient creates a new VirtualHere client that communicates via IPC (named pipe/socket)
 // without requiring a binary path. This is the recommended method when you want to communicate
 // with an already-running VirtualHere service.
@@ -48,11 +78,32 @@ func WithOnProcessTerminated(callback func()) ClientOption {
 //   - Windows: Named pipe at \\.\pipe\vhclient
 //   - Linux/macOS: Unix sockets at /tmp/vhclient and /tmp/vhclient_response
 //
+// The socket locations can be changed with WithSocketDir, WithSocketPaths or the
+// VHCLIENT_SOCKET_DIR environment variable; such sockets must already exist.
+//
 // Note: WithService option is not supported with NewPipeClient since no binary path is provided.
-func NewPipeClient() (*Client, error) {
+func NewPipeClient(opts ...ClientOption) (*Client, error) {
 	client := &Client{
 		binaryPath: "", // No binary path needed for pipe-only communication
 	}
+	if err := client.applyOptions(opts); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClientWithTransport creates a VirtualHere client that sends every command
+// through the given Transport instead of the platform IPC channel.
+// Like NewPipeClient, it does not manage a service process.
+func NewClientWithTransport(transport Transport, opts ...ClientOption) (*Client, error) {
+	if transport == nil {
+		return nil, fmt.Errorf("transport must not be nil")
+	}
+
+	client := &Client{}
+	if err := client.applyOptions(append(opts, WithTransport(transport))); err != nil {
+		return nil, err
+	}
 	return client, nil
 }
 
@@ -84,8 +135,8 @@ func NewClient(binaryPath string, opts ...ClientOption) (*Client, error) {
 	}
 
 	// Apply options
-	for _, opt := range opts {
-		opt(client)
+	if err := client.applyOptions(opts); err != nil {
+		return nil, err
 	}
 
 	// Start service if enabled
@@ -98,6 +149,35 @@ func NewClient(binaryPath string, opts ...ClientOption) (*Client, error) {
 	return client, nil
 }
 
+// applyOptions applies opts and fills in defaults for anything left unset.
+// Explicitly configured sockets are validated unless the client is about to
+// start the service that creates them.
+func (c *Client) applyOptions(opts []ClientOption) error {
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.transport != nil {
+		return nil
+	}
+
+	if !c.resolveSocketPaths() {
+		c.transport = DefaultTransport()
+		return nil
+	}
+
+	transport := &UnixSocketTransport{
+		RequestPath:  c.requestPath,
+		ResponsePath: c.responsePath,
+	}
+	c.transport = transport
+
+	if c.runService {
+		return nil
+	}
+	return validateSocketTransport(transport)
+}
+
 // startService starts the VirtualHere client as a background service
 func (c *Client) startService() error {
 	c.serviceMu.Lock()
@@ -193,21 +273,59 @@ func (c *Client) Close() error {
 	return nil
 }
 
-// executeCommand sends a command to the VirtualHere client via named pipe (Windows)
-// or Unix socket (Linux/macOS) and returns the response
-func (c *Client) executeCommand(command string) (*CommandResult, error) {
+// executeCommand sends a command to the VirtualHere client through the configured
+// Transport (by default named pipe on Windows, Unix socket on Linux/macOS)
+// and returns the response.
+// Commands are serialized through the client's queue in priority order.
+// Cancelling ctx or reaching its deadline aborts the exchange, or the wait for it.
+func (c *Client) executeCommand(ctx context.Context, cmd Command) (*CommandResult, error) {
+	if c.metrics != nil {
+		c.metrics.CommandStarted(cmd.Verb)
+	}
+
+	start := time.Now()
+	result, err := c.runCommand(ctx, cmd)
+
+	c.logCommand(ctx, cmd, start, result, err)
+	if c.metrics != nil {
+		c.metrics.CommandFinished(cmd.Verb, commandOutcome(result, err), time.Since(start))
+	}
+	return result, err
+}
+
+// runCommand exchanges cmd, retrying transient failures, and interprets the response
+func (c *Client) runCommand(ctx context.Context, cmd Command) (*CommandResult, error) {
 	result := &CommandResult{}
+	command := cmd.String()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to communicate with client: %w", err)
+	}
 
 	var response string
 	var err error
+	attempts := 0
 
-	if runtime.GOOS == "windows" {
-		response, err = c.executeCommandWindows(command)
-	} else {
-		response, err = c.executeCommandUnix(command)
+	for {
+		attempts++
+		response, err = c.send(ctx, cmd.spec().Priority, command)
+		if err == nil || !c.retry.shouldRetry(cmd, err, attempts) {
+			break
+		}
+
+		// The queue is released while backing off so other commands can proceed
+		if sleepErr := sleepContext(ctx, c.retry.backoff(attempts)); sleepErr != nil {
+			err = fmt.Errorf("%w: %w", sleepErr, err)
+			break
+		}
 	}
 
 	if err != nil {
+		// Transport errors may quote the command, secrets included
+		err = redactError(cmd, err)
+		if c.retry.enabled() {
+			err = &RetryError{Verb: cmd.Verb, Attempts: attempts, Err: err}
+		}
 		return nil, fmt.Errorf("failed to communicate with client: %w", err)
 	}
 
@@ -221,15 +339,10 @@ func (c *Client) executeCommand(command string) (*CommandResult, error) {
 		return result, nil
 	}
 
-	if response == "FAILED" {
+	// Rejections are reported as a *CommandError classified from the response
+	if response == "FAILED" || strings.HasPrefix(response, "ERROR:") {
 		result.Success = false
-		result.Error = ErrCommandFailed
-		return result, nil
-	}
-
-	if strings.HasPrefix(response, "ERROR:") {
-		result.Success = false
-		result.Error = fmt.Errorf("%s", strings.TrimSpace(strings.TrimPrefix(response, "ERROR:")))
+		result.Error = newCommandError(cmd, response)
 		return result, nil
 	}
 
@@ -238,6 +351,35 @@ func (c *Client) executeCommand(command string) (*CommandResult, error) {
 	return result, nil
 }
 
+// send performs a single exchange through the transport once the queue
+// hands the channel to this command
+func (c *Client) send(ctx context.Context, priority Priority, command string) (string, error) {
+	if err := c.queue.acquire(ctx, contextPriority(ctx, priority)); err != nil {
+		return "", err
+	}
+	defer c.queue.release()
+
+	return c.transport.Send(ctx, command)
+}
+
+// commandDeadline returns the earlier of now+timeout and the deadline of ctx
+func commandDeadline(ctx context.Context, timeout time.Duration) time.Time {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+	return deadline
+}
+
+// contextError returns the context error if ctx is done, otherwise err.
+// It is used to report cancellation instead of the I/O error it caused.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
 // GetBinaryPath returns the path to the VirtualHere binary
 func (c *Client) GetBinaryPath() string {
 	return c.binaryPath
@@ -0,0 +1,166 @@
+//go:build !windows
+// +build !windows
+
+package virtualhere_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Tryanks/virtualhere-go"
+	"github.com/Tryanks/virtualhere-go/vhtest"
+)
+
+// newServer starts a fake daemon that is closed when the test ends
+func newServer(t *testing.T) *vhtest.Server {
+	t.Helper()
+	srv, err := vhtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// waitQueued blocks until n commands of c wait for the IPC channel
+func waitQueued(t *testing.T, c *virtualhere.Client, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for virtualhere.QueueLen(c) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d command(s) queued, want %d", virtualhere.QueueLen(c), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrentCommands(t *testing.T) {
+	const devices = 8
+	srv := newServer(t)
+	srv.AddHub(vhtest.Hub{Name: "Raspberry Hub", Address: "raspberrypi:7575"})
+	for i := 1; i <= devices; i++ {
+		srv.AddDevice("raspberrypi:7575", vhtest.Device{Address: i, Product: fmt.Sprintf("Device %d", i), Serial: fmt.Sprintf("SN%d", i)})
+	}
+	client, err := srv.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 10 {
+				if (g+i)%2 == 0 {
+					state, err := client.List()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if len(state.Hubs) != 1 || len(state.Hubs[0].Devices) != devices {
+						t.Errorf("LIST returned %+v, want 1 hub with %d devices", state.Hubs, devices)
+					}
+					continue
+				}
+
+				n := (g+i)%devices + 1
+				address := fmt.Sprintf("raspberrypi.%d", n)
+				info, err := client.DeviceInfo(address)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if info.Address != address || info.Serial != fmt.Sprintf("SN%d", n) {
+					t.Errorf("DEVICE INFO %s returned %+v", address, info)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestStopUsingJumpsQueue(t *testing.T) {
+	srv := newServer(t)
+	srv.AddHub(vhtest.Hub{Name: "Raspberry Hub", Address: "raspberrypi:7575"})
+	srv.AddDevice("raspberrypi:7575", vhtest.Device{Address: 114, Product: "FT232R", InUseBy: vhtest.ClientHostname})
+
+	// The first command blocks in the transport until released, so that the
+	// following ones pile up in the queue behind it
+	socket := &virtualhere.UnixSocketTransport{RequestPath: srv.RequestPath, ResponsePath: srv.ResponsePath}
+	started, release := make(chan struct{}), make(chan struct{})
+	var blocked atomic.Bool
+	transport := virtualhere.TransportFunc(func(ctx context.Context, command string) (string, error) {
+		if blocked.CompareAndSwap(false, true) {
+			close(started)
+			<-release
+		}
+		return socket.Send(ctx, command)
+	})
+	client, err := virtualhere.NewClientWithTransport(transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const backlog = 5
+	var wg sync.WaitGroup
+	run := func(f func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	run(func() error { _, err := client.List(); return err })
+	<-started
+
+	low := virtualhere.WithPriority(context.Background(), virtualhere.PriorityLow)
+	for range backlog {
+		run(func() error { _, err := client.ListContext(low); return err })
+	}
+	waitQueued(t, client, backlog)
+
+	run(func() error { return client.StopUsing("raspberrypi.114") })
+	waitQueued(t, client, backlog+1)
+
+	close(release)
+	wg.Wait()
+
+	commands := srv.Commands()
+	if len(commands) != backlog+2 {
+		t.Fatalf("daemon received %q, want %d commands", commands, backlog+2)
+	}
+	if commands[1] != "STOP USING,raspberrypi.114" {
+		t.Fatalf("daemon received %q, want STOP USING served right after the first command", commands)
+	}
+	for _, command := range commands[2:] {
+		if !strings.HasPrefix(command, "LIST") {
+			t.Fatalf("daemon received %q after STOP USING, want the LIST backlog", command)
+		}
+	}
+}
+
+func TestUsePasswordWithComma(t *testing.T) {
+	srv := newServer(t)
+	srv.AddHub(vhtest.Hub{Name: "Raspberry Hub", Address: "raspberrypi:7575"})
+	srv.AddDevice("raspberrypi:7575", vhtest.Device{Address: 114, Product: "FT232R", Password: "pa,ss"})
+	client, err := srv.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Use("raspberrypi.114", "pa,ss"); err != nil {
+		t.Fatalf("Use with a comma in the password: %v", err)
+	}
+	if commands := srv.Commands(); commands[len(commands)-1] != "USE,raspberrypi.114,pa,ss" {
+		t.Errorf("daemon received %q", commands)
+	}
+}
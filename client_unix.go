@@ -4,58 +4,77 @@
 package virtualhere
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
 	"time"
 )
 
-// executeCommandWindows is a stub for Unix systems (not used)
-func (c *Client) executeCommandWindows(command string) (string, error) {
+// DefaultTransport returns the IPC transport for the current platform,
+// the Unix domain sockets at their default locations
+func DefaultTransport() Transport {
+	return &UnixSocketTransport{}
+}
+
+// Send is a stub for Unix systems (not used)
+func (t *NamedPipeTransport) Send(ctx context.Context, command string) (string, error) {
 	return "", fmt.Errorf("Windows named pipe is not supported on this platform")
 }
 
-// executeCommandUnix sends a command via Unix domain socket (Linux/macOS)
+// Send sends a command via Unix domain socket (Linux/macOS)
 // The client uses two separate socket files:
-// - /tmp/vhclient for sending requests
-// - /tmp/vhclient_response for receiving responses
-func (c *Client) executeCommandUnix(command string) (string, error) {
-	requestPath := "/tmp/vhclient"
-	responsePath := "/tmp/vhclient_response"
+// - RequestPath (/tmp/vhclient) for sending requests
+// - ResponsePath (/tmp/vhclient_response) for receiving responses
+//
+// The dial, write and read all honour ctx: its deadline shortens the default
+// timeouts and cancelling it closes both sockets to unblock pending I/O.
+func (t *UnixSocketTransport) Send(ctx context.Context, command string) (string, error) {
+	requestPath := t.requestPath()
+	responsePath := t.responsePath()
+
+	dialer := net.Dialer{Timeout: 2 * time.Second}
 
 	// Open response socket first and wait for data
-	responseConn, err := net.DialTimeout("unix", responsePath, 2*time.Second)
+	responseConn, err := dialer.DialContext(ctx, "unix", responsePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to connect to response socket: %w", err)
 	}
 	defer responseConn.Close()
 
 	// Connect to request socket
-	conn, err := net.DialTimeout("unix", requestPath, 2*time.Second)
+	conn, err := dialer.DialContext(ctx, "unix", requestPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to connect to request socket: %w", err)
 	}
 	defer conn.Close()
 
+	// Close both sockets as soon as ctx is cancelled so blocked I/O returns
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+		responseConn.Close()
+	})
+	defer stop()
+
 	// Set write deadline
-	if err := conn.SetWriteDeadline(time.Now().Add(2 * time.Second)); err != nil {
-		return "", fmt.Errorf("failed to set write deadline: %w", err)
+	if err := conn.SetWriteDeadline(commandDeadline(ctx, 2*time.Second)); err != nil {
+		return "", fmt.Errorf("failed to set write deadline: %w", contextError(ctx, err))
 	}
 
 	// Write command with newline (required by VirtualHere protocol)
 	if _, err := conn.Write([]byte(command + "\n")); err != nil {
-		return "", fmt.Errorf("failed to write command: %w", err)
+		return "", fmt.Errorf("failed to write command: %w", contextError(ctx, err))
 	}
 
 	// Set read deadline (5 seconds as per VirtualHere API documentation)
-	if err := responseConn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
-		return "", fmt.Errorf("failed to set read deadline: %w", err)
+	if err := responseConn.SetReadDeadline(commandDeadline(ctx, 5*time.Second)); err != nil {
+		return "", fmt.Errorf("failed to set read deadline: %w", contextError(ctx, err))
 	}
 
 	// Read response - VirtualHere sends complete response
 	response, err := io.ReadAll(responseConn)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return "", fmt.Errorf("failed to read response: %w", contextError(ctx, err))
 	}
 
 	return string(response), nil
@@ -4,6 +4,7 @@
 package virtualhere
 
 import (
+	"context"
 	"fmt"
 	"syscall"
 	"time"
@@ -29,9 +30,17 @@ var (
 	closeHandle             = kernel32.NewProc("CloseHandle")
 )
 
-// executeCommandWindows sends a command via Windows named pipe
-func (c *Client) executeCommandWindows(command string) (string, error) {
-	pipePath := `\\.\pipe\vhclient`
+// DefaultTransport returns the IPC transport for the current platform,
+// the named pipe at its default location
+func DefaultTransport() Transport {
+	return &NamedPipeTransport{}
+}
+
+// Send sends a command via Windows named pipe.
+// Opening the pipe stops retrying once ctx is done; the blocking write and
+// read run in a goroutine so that a cancelled ctx returns immediately.
+func (t *NamedPipeTransport) Send(ctx context.Context, command string) (string, error) {
+	pipePath := t.path()
 	pipePathPtr, err := syscall.UTF16PtrFromString(pipePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to convert pipe path: %w", err)
@@ -39,9 +48,13 @@ func (c *Client) executeCommandWindows(command string) (string, error) {
 
 	// Try to open the pipe
 	var hPipe uintptr
-	timeout := time.Now().Add(5 * time.Second)
+	timeout := commandDeadline(ctx, 5*time.Second)
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("failed to connect to named pipe: %w", err)
+		}
+
 		handle, _, _ := createFileW.Call(
 			uintptr(unsafe.Pointer(pipePathPtr)),
 			GENERIC_READ|GENERIC_WRITE,
@@ -75,11 +88,33 @@ func (c *Client) executeCommandWindows(command string) (string, error) {
 			continue
 		}
 
-		return "", fmt.Errorf("failed to open named pipe: %v", lastErr)
+		return "", fmt.Errorf("failed to open named pipe: %w", lastErr)
 	}
 
-	defer closeHandle.Call(hPipe)
+	type pipeResult struct {
+		response string
+		err      error
+	}
+
+	// The handle is owned by the goroutine, which closes it once the
+	// exchange finishes even if ctx was cancelled in the meantime
+	done := make(chan pipeResult, 1)
+	go func() {
+		defer closeHandle.Call(hPipe)
+		response, err := exchangeNamedPipe(hPipe, command)
+		done <- pipeResult{response: response, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.response, res.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("failed to read from pipe: %w", ctx.Err())
+	}
+}
 
+// exchangeNamedPipe writes command to an open pipe handle and reads the response
+func exchangeNamedPipe(hPipe uintptr, command string) (string, error) {
 	// Set pipe to MESSAGE mode (required by VirtualHere)
 	// This matches the C++ example from the API docs:
 	// DWORD dwMode = PIPE_READMODE_MESSAGE;
@@ -127,7 +162,7 @@ func (c *Client) executeCommandWindows(command string) (string, error) {
 	return string(buffer[:bytesRead]), nil
 }
 
-// executeCommandUnix is a stub for Windows (not used)
-func (c *Client) executeCommandUnix(command string) (string, error) {
+// Send is a stub for Windows (not used)
+func (t *UnixSocketTransport) Send(ctx context.Context, command string) (string, error) {
 	return "", fmt.Errorf("Unix domain sockets are not supported on Windows")
 }
@@ -0,0 +1,239 @@
+package virtualhere
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ArgSpec describes one argument of a command
+type ArgSpec struct {
+	Name     string // e.g. "address"
+	Optional bool   // May be omitted (only trailing arguments)
+	Secret   bool   // Redacted in logs, errors and transcripts
+	Rest     bool   // Read by the daemon up to the end of the line, so it may contain commas (last argument only)
+}
+
+// CommandSpec describes a command verb supported by the VirtualHere client
+type CommandSpec struct {
+	Verb     string    // e.g. "DEVICE RENAME"
+	Args     []ArgSpec // Arguments in order
+	Toggle   bool      // Flips a setting each time it is sent, so it is never retried
+	Priority Priority  // Default queue priority, see WithPriority
+}
+
+// commandSpecs is the registry of every command the library knows how to send
+var commandSpecs = []CommandSpec{
+	{Verb: "LIST"},
+	{Verb: "GET CLIENT STATE"},
+	{Verb: "USE", Args: []ArgSpec{{Name: "address"}, {Name: "password", Optional: true, Secret: true, Rest: true}}},
+	{Verb: "STOP USING", Args: []ArgSpec{{Name: "address"}}, Priority: PriorityHigh},
+	{Verb: "STOP USING ALL", Args: []ArgSpec{{Name: "server address", Optional: true}}, Priority: PriorityHigh},
+	{Verb: "STOP USING ALL LOCAL", Priority: PriorityHigh},
+	{Verb: "DEVICE INFO", Args: []ArgSpec{{Name: "address"}}},
+	{Verb: "SERVER INFO", Args: []ArgSpec{{Name: "server name"}}},
+	{Verb: "DEVICE RENAME", Args: []ArgSpec{{Name: "address"}, {Name: "nickname"}}},
+	{Verb: "SERVER RENAME", Args: []ArgSpec{{Name: "hub address"}, {Name: "name"}}},
+	{Verb: "AUTO USE ALL", Toggle: true},
+	{Verb: "AUTO USE HUB", Args: []ArgSpec{{Name: "server name"}}, Toggle: true},
+	{Verb: "AUTO USE PORT", Args: []ArgSpec{{Name: "address"}}, Toggle: true},
+	{Verb: "AUTO USE DEVICE", Args: []ArgSpec{{Name: "address"}}, Toggle: true},
+	{Verb: "AUTO USE DEVICE PORT", Args: []ArgSpec{{Name: "address"}}, Toggle: true},
+	{Verb: "AUTO USE CLEAR ALL"},
+	{Verb: "MANUAL HUB ADD", Args: []ArgSpec{{Name: "address"}}},
+	{Verb: "MANUAL HUB REMOVE", Args: []ArgSpec{{Name: "address"}}},
+	{Verb: "MANUAL HUB REMOVE ALL"},
+	{Verb: "MANUAL HUB LIST"},
+	{Verb: "ADD REVERSE", Args: []ArgSpec{{Name: "server serial"}, {Name: "client address"}}},
+	{Verb: "REMOVE REVERSE", Args: []ArgSpec{{Name: "server serial"}, {Name: "client address"}}},
+	{Verb: "LIST REVERSE", Args: []ArgSpec{{Name: "server serial"}}},
+	{Verb: "LIST LICENSES"},
+	{Verb: "LICENSE SERVER", Args: []ArgSpec{{Name: "license key", Secret: true, Rest: true}}},
+	{Verb: "CLEAR LOG"},
+	{Verb: "CUSTOM EVENT", Args: []ArgSpec{{Name: "address"}, {Name: "event"}}},
+	{Verb: "AUTOFIND", Toggle: true},
+	{Verb: "REVERSE", Toggle: true},
+	{Verb: "SSLREVERSE", Toggle: true},
+	{Verb: "EXIT"},
+	{Verb: "HELP"},
+}
+
+// commandIndex maps verbs to their entry in commandSpecs
+var commandIndex = func() map[string]*CommandSpec {
+	index := make(map[string]*CommandSpec, len(commandSpecs))
+	for i := range commandSpecs {
+		index[commandSpecs[i].Verb] = &commandSpecs[i]
+	}
+	return index
+}()
+
+// LookupCommand returns the specification of verb, if it is registered
+func LookupCommand(verb string) (CommandSpec, bool) {
+	spec, ok := commandIndex[verb]
+	if !ok {
+		return CommandSpec{}, false
+	}
+	return *spec, true
+}
+
+// Commands returns the specifications of every registered command
+func Commands() []CommandSpec {
+	return append([]CommandSpec(nil), commandSpecs...)
+}
+
+// Command is a VirtualHere client command: a registered verb and its arguments.
+// Build commands with NewCommand so that arguments are validated before encoding.
+type Command struct {
+	Verb string
+	Args []string
+}
+
+// NewCommand returns the command verb with args, validated against the verb's
+// schema. Omitted optional arguments may be passed as empty strings. Arguments
+// containing a line break, or a comma where the protocol cannot carry one, are
+// rejected with ErrInvalidArgument since they would corrupt the command or
+// inject a second one.
+func NewCommand(verb string, args ...string) (Command, error) {
+	spec, ok := commandIndex[verb]
+	if !ok {
+		return Command{}, fmt.Errorf("%w: %q", ErrUnknownCommand, verb)
+	}
+
+	// Trailing optional arguments left empty are omitted
+	for len(args) > 0 && len(args) <= len(spec.Args) && args[len(args)-1] == "" && spec.Args[len(args)-1].Optional {
+		args = args[:len(args)-1]
+	}
+
+	if len(args) > len(spec.Args) {
+		return Command{}, fmt.Errorf("%w: %s takes at most %d argument(s), got %d", ErrInvalidArgument, verb, len(spec.Args), len(args))
+	}
+
+	for i, arg := range spec.Args {
+		if i >= len(args) {
+			if !arg.Optional {
+				return Command{}, fmt.Errorf("%w: %s requires %s", ErrInvalidArgument, verb, arg.Name)
+			}
+			continue
+		}
+		if err := arg.validate(args[i]); err != nil {
+			return Command{}, fmt.Errorf("%w: %s %s %v", ErrInvalidArgument, verb, arg.Name, err)
+		}
+	}
+
+	return Command{Verb: verb, Args: append([]string(nil), args...)}, nil
+}
+
+// validate checks that value can be sent as this argument
+func (a ArgSpec) validate(value string) error {
+	if value == "" && !a.Optional {
+		return fmt.Errorf("must not be empty")
+	}
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return fmt.Errorf("must not contain a line break")
+	}
+	if !a.Rest && strings.Contains(value, ",") {
+		return fmt.Errorf("must not contain a comma")
+	}
+	return nil
+}
+
+// spec returns the registered specification of the command's verb
+func (c Command) spec() CommandSpec {
+	spec, _ := LookupCommand(c.Verb)
+	return spec
+}
+
+// String encodes the command as sent to the VirtualHere client, without the
+// terminating newline
+func (c Command) String() string {
+	if len(c.Args) == 0 {
+		return c.Verb
+	}
+	return c.Verb + "," + strings.Join(c.Args, ",")
+}
+
+// Redacted encodes the command like String with secret arguments replaced
+func (c Command) Redacted() string {
+	spec := c.spec()
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		if i < len(spec.Args) && spec.Args[i].Secret && arg != "" {
+			arg = redacted
+		}
+		args[i] = arg
+	}
+	return Command{Verb: c.Verb, Args: args}.String()
+}
+
+// secrets returns the values of the command's secret arguments
+func (c Command) secrets() []string {
+	spec := c.spec()
+	var secrets []string
+	for i, arg := range c.Args {
+		if i < len(spec.Args) && spec.Args[i].Secret && arg != "" {
+			secrets = append(secrets, arg)
+		}
+	}
+	return secrets
+}
+
+// parseCommand splits an encoded command line into its verb and arguments
+// using the registry, so that a Rest argument keeps its commas. Unregistered
+// verbs are split at every comma.
+func parseCommand(line string) Command {
+	var spec *CommandSpec
+	for i := range commandSpecs {
+		candidate := &commandSpecs[i]
+		if line != candidate.Verb && !strings.HasPrefix(line, candidate.Verb+",") {
+			continue
+		}
+		if spec == nil || len(candidate.Verb) > len(spec.Verb) {
+			spec = candidate
+		}
+	}
+
+	if spec == nil {
+		verb, rest, ok := strings.Cut(line, ",")
+		if !ok {
+			return Command{Verb: verb}
+		}
+		return Command{Verb: verb, Args: strings.Split(rest, ",")}
+	}
+
+	if line == spec.Verb {
+		return Command{Verb: spec.Verb}
+	}
+
+	rest := line[len(spec.Verb)+1:]
+	n := len(spec.Args)
+	if n == 0 {
+		n = -1
+	}
+	return Command{Verb: spec.Verb, Args: strings.SplitN(rest, ",", n)}
+}
+
+// execute builds the command verb with args and executes it
+func (c *Client) execute(ctx context.Context, verb string, args ...string) (*CommandResult, error) {
+	cmd, err := NewCommand(verb, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkSupported(ctx, cmd); err != nil {
+		return nil, err
+	}
+	return c.executeCommand(ctx, cmd)
+}
+
+// Execute sends a command built with NewCommand and returns the raw result.
+// Use it for commands that have no dedicated method. Like every command, it
+// fails with ErrUnsupported if the client's capabilities are known and do
+// not list the verb.
+func (c *Client) Execute(ctx context.Context, cmd Command) (*CommandResult, error) {
+	if _, err := NewCommand(cmd.Verb, cmd.Args...); err != nil {
+		return nil, err
+	}
+	if err := c.checkSupported(ctx, cmd); err != nil {
+		return nil, err
+	}
+	return c.executeCommand(ctx, cmd)
+}
@@ -0,0 +1,126 @@
+package virtualhere
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewCommand(t *testing.T) {
+	tests := []struct {
+		verb string
+		args []string
+		want string
+	}{
+		{"LIST", nil, "LIST"},
+		{"USE", []string{"raspberrypi.114"}, "USE,raspberrypi.114"},
+		{"USE", []string{"raspberrypi.114", ""}, "USE,raspberrypi.114"},
+		{"USE", []string{"raspberrypi.114", "secret"}, "USE,raspberrypi.114,secret"},
+		{"USE", []string{"raspberrypi.114", "pa,ss"}, "USE,raspberrypi.114,pa,ss"},
+		{"STOP USING ALL", []string{""}, "STOP USING ALL"},
+		{"DEVICE RENAME", []string{"raspberrypi.114", "jtag 1"}, "DEVICE RENAME,raspberrypi.114,jtag 1"},
+		{"LICENSE SERVER", []string{"S1,0,sig,with,commas"}, "LICENSE SERVER,S1,0,sig,with,commas"},
+	}
+	for _, tt := range tests {
+		cmd, err := NewCommand(tt.verb, tt.args...)
+		if err != nil {
+			t.Errorf("NewCommand(%q, %q): %v", tt.verb, tt.args, err)
+			continue
+		}
+		if got := cmd.String(); got != tt.want {
+			t.Errorf("NewCommand(%q, %q) = %q, want %q", tt.verb, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestNewCommandRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		verb string
+		args []string
+		want error
+	}{
+		{"unknown verb", "FORMAT DISK", nil, ErrUnknownCommand},
+		{"comma", "DEVICE RENAME", []string{"raspberrypi.114", "a,b"}, ErrInvalidArgument},
+		{"comma in address", "USE", []string{"raspberrypi.114,evil"}, ErrInvalidArgument},
+		{"newline", "DEVICE RENAME", []string{"raspberrypi.114", "a\nEXIT"}, ErrInvalidArgument},
+		{"carriage return", "SERVER RENAME", []string{"raspberrypi:7575", "a\rb"}, ErrInvalidArgument},
+		{"NUL", "USE", []string{"raspberrypi.114", "pa\x00ss"}, ErrInvalidArgument},
+		{"newline in rest argument", "LICENSE SERVER", []string{"S1,0,sig\nEXIT"}, ErrInvalidArgument},
+		{"missing argument", "DEVICE RENAME", []string{"raspberrypi.114"}, ErrInvalidArgument},
+		{"empty required argument", "DEVICE INFO", []string{""}, ErrInvalidArgument},
+		{"empty argument before the last", "DEVICE RENAME", []string{"", "jtag"}, ErrInvalidArgument},
+		{"too many arguments", "STOP USING", []string{"raspberrypi.114", "x"}, ErrInvalidArgument},
+		{"argument to a bare verb", "LIST", []string{"x"}, ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCommand(tt.verb, tt.args...); !errors.Is(err, tt.want) {
+				t.Errorf("NewCommand(%q, %q) returned %v, want %v", tt.verb, tt.args, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCommandRoundTrip(t *testing.T) {
+	tests := []struct {
+		verb     string
+		args     []string
+		redacted string
+	}{
+		{"LIST", nil, "LIST"},
+		{"STOP USING ALL LOCAL", nil, "STOP USING ALL LOCAL"},
+		{"STOP USING ALL", []string{"raspberrypi:7575"}, "STOP USING ALL,raspberrypi:7575"},
+		{"STOP USING", []string{"raspberrypi.114"}, "STOP USING,raspberrypi.114"},
+		{"AUTO USE DEVICE PORT", []string{"raspberrypi.114"}, "AUTO USE DEVICE PORT,raspberrypi.114"},
+		{"USE", []string{"raspberrypi.114"}, "USE,raspberrypi.114"},
+		{"USE", []string{"raspberrypi.114", "pa,ss"}, "USE,raspberrypi.114," + redacted},
+		{"LICENSE SERVER", []string{"S1,0,signature"}, "LICENSE SERVER," + redacted},
+		{"DEVICE RENAME", []string{"raspberrypi.114", "jtag-1"}, "DEVICE RENAME,raspberrypi.114,jtag-1"},
+	}
+	for _, tt := range tests {
+		cmd, err := NewCommand(tt.verb, tt.args...)
+		if err != nil {
+			t.Fatalf("NewCommand(%q, %q): %v", tt.verb, tt.args, err)
+		}
+
+		parsed := parseCommand(cmd.String())
+		if parsed.Verb != cmd.Verb || !slices.Equal(parsed.Args, cmd.Args) {
+			t.Errorf("parseCommand(%q) = %q %q, want %q %q", cmd.String(), parsed.Verb, parsed.Args, cmd.Verb, cmd.Args)
+		}
+
+		if got := cmd.Redacted(); got != tt.redacted {
+			t.Errorf("%q redacted as %q, want %q", cmd.String(), got, tt.redacted)
+		}
+		if got := parsed.Redacted(); got != tt.redacted {
+			t.Errorf("parsed %q redacted as %q, want %q", cmd.String(), got, tt.redacted)
+		}
+		for _, secret := range cmd.secrets() {
+			if strings.Contains(cmd.Redacted(), secret) {
+				t.Errorf("%q leaks secret %q", cmd.Redacted(), secret)
+			}
+		}
+	}
+
+	if got := parseCommand("FUTURE VERB,a,b"); got.Verb != "FUTURE VERB" || !slices.Equal(got.Args, []string{"a", "b"}) {
+		t.Errorf("parseCommand of an unregistered verb = %q %q", got.Verb, got.Args)
+	}
+}
+
+func TestCommandRegistry(t *testing.T) {
+	for _, spec := range Commands() {
+		for i, arg := range spec.Args {
+			last := i == len(spec.Args)-1
+			if arg.Rest && !last {
+				t.Errorf("%s: Rest argument %q is not the last", spec.Verb, arg.Name)
+			}
+			if arg.Optional && !last && !spec.Args[i+1].Optional {
+				t.Errorf("%s: optional argument %q precedes a required one", spec.Verb, arg.Name)
+			}
+		}
+		if got, ok := LookupCommand(spec.Verb); !ok || got.Verb != spec.Verb {
+			t.Errorf("LookupCommand(%q) = %+v, %v", spec.Verb, got, ok)
+		}
+	}
+}
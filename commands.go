@@ -1,13 +1,18 @@
 package virtualhere
 
 import (
-	"fmt"
+	"context"
 	"strings"
 )
 
 // List returns a list of all available devices and hubs
 func (c *Client) List() (*ClientState, error) {
-	result, err := c.executeCommand("LIST")
+	return c.ListContext(context.Background())
+}
+
+// ListContext is like List but uses ctx to cancel or time out the command
+func (c *Client) ListContext(ctx context.Context) (*ClientState, error) {
+	result, err := c.execute(ctx, "LIST")
 	if err != nil {
 		return nil, err
 	}
@@ -16,12 +21,17 @@ func (c *Client) List() (*ClientState, error) {
 		return nil, result.Error
 	}
 
-	return parseListOutput(result.Output)
+	return parseListOutput(result.Output, c.strict)
 }
 
 // GetClientState returns the detailed full client state as an XML document
 func (c *Client) GetClientState() (*XMLClientState, error) {
-	result, err := c.executeCommand("GET CLIENT STATE")
+	return c.GetClientStateContext(context.Background())
+}
+
+// GetClientStateContext is like GetClientState but uses ctx to cancel or time out the command
+func (c *Client) GetClientStateContext(ctx context.Context) (*XMLClientState, error) {
+	result, err := c.execute(ctx, "GET CLIENT STATE")
 	if err != nil {
 		return nil, err
 	}
@@ -30,21 +40,19 @@ func (c *Client) GetClientState() (*XMLClientState, error) {
 		return nil, result.Error
 	}
 
-	return parseClientStateXML(result.Output)
+	return parseClientStateXML(result.Output, c.strict)
 }
 
 // Use connects to and uses a remote device
 // address: device address (e.g., "raspberrypi.114")
 // password: optional password for the device (empty string if none)
 func (c *Client) Use(address string, password string) error {
-	var command string
-	if password != "" {
-		command = fmt.Sprintf("USE,%s,%s", address, password)
-	} else {
-		command = fmt.Sprintf("USE,%s", address)
-	}
+	return c.UseContext(context.Background(), address, password)
+}
 
-	result, err := c.executeCommand(command)
+// UseContext is like Use but uses ctx to cancel or time out the command
+func (c *Client) UseContext(ctx context.Context, address string, password string) error {
+	result, err := c.execute(ctx, "USE", address, password)
 	if err != nil {
 		return err
 	}
@@ -58,7 +66,12 @@ func (c *Client) Use(address string, password string) error {
 
 // StopUsing disconnects from a device
 func (c *Client) StopUsing(address string) error {
-	result, err := c.executeCommand(fmt.Sprintf("STOP USING,%s", address))
+	return c.StopUsingContext(context.Background(), address)
+}
+
+// StopUsingContext is like StopUsing but uses ctx to cancel or time out the command
+func (c *Client) StopUsingContext(ctx context.Context, address string) error {
+	result, err := c.execute(ctx, "STOP USING", address)
 	if err != nil {
 		return err
 	}
@@ -74,14 +87,12 @@ func (c *Client) StopUsing(address string) error {
 // If serverAddress is empty, stops all devices on all servers
 // serverAddress can be in format "address:port" or "EasyFind address"
 func (c *Client) StopUsingAll(serverAddress string) error {
-	var command string
-	if serverAddress != "" {
-		command = fmt.Sprintf("STOP USING ALL,%s", serverAddress)
-	} else {
-		command = "STOP USING ALL"
-	}
+	return c.StopUsingAllContext(context.Background(), serverAddress)
+}
 
-	result, err := c.executeCommand(command)
+// StopUsingAllContext is like StopUsingAll but uses ctx to cancel or time out the command
+func (c *Client) StopUsingAllContext(ctx context.Context, serverAddress string) error {
+	result, err := c.execute(ctx, "STOP USING ALL", serverAddress)
 	if err != nil {
 		return err
 	}
@@ -95,7 +106,12 @@ func (c *Client) StopUsingAll(serverAddress string) error {
 
 // StopUsingAllLocal stops using all devices just for this client
 func (c *Client) StopUsingAllLocal() error {
-	result, err := c.executeCommand("STOP USING ALL LOCAL")
+	return c.StopUsingAllLocalContext(context.Background())
+}
+
+// StopUsingAllLocalContext is like StopUsingAllLocal but uses ctx to cancel or time out the command
+func (c *Client) StopUsingAllLocalContext(ctx context.Context) error {
+	result, err := c.execute(ctx, "STOP USING ALL LOCAL")
 	if err != nil {
 		return err
 	}
@@ -109,7 +125,12 @@ func (c *Client) StopUsingAllLocal() error {
 
 // DeviceInfo returns information about a specific device
 func (c *Client) DeviceInfo(address string) (*DeviceInfo, error) {
-	result, err := c.executeCommand(fmt.Sprintf("DEVICE INFO,%s", address))
+	return c.DeviceInfoContext(context.Background(), address)
+}
+
+// DeviceInfoContext is like DeviceInfo but uses ctx to cancel or time out the command
+func (c *Client) DeviceInfoContext(ctx context.Context, address string) (*DeviceInfo, error) {
+	result, err := c.execute(ctx, "DEVICE INFO", address)
 	if err != nil {
 		return nil, err
 	}
@@ -118,12 +139,17 @@ func (c *Client) DeviceInfo(address string) (*DeviceInfo, error) {
 		return nil, result.Error
 	}
 
-	return parseDeviceInfo(result.Output)
+	return parseDeviceInfo(result.Output, c.strict)
 }
 
 // ServerInfo returns information about a specific server
 func (c *Client) ServerInfo(serverName string) (*ServerInfo, error) {
-	result, err := c.executeCommand(fmt.Sprintf("SERVER INFO,%s", serverName))
+	return c.ServerInfoContext(context.Background(), serverName)
+}
+
+// ServerInfoContext is like ServerInfo but uses ctx to cancel or time out the command
+func (c *Client) ServerInfoContext(ctx context.Context, serverName string) (*ServerInfo, error) {
+	result, err := c.execute(ctx, "SERVER INFO", serverName)
 	if err != nil {
 		return nil, err
 	}
@@ -132,12 +158,17 @@ func (c *Client) ServerInfo(serverName string) (*ServerInfo, error) {
 		return nil, result.Error
 	}
 
-	return parseServerInfo(result.Output)
+	return parseServerInfo(result.Output, c.strict)
 }
 
 // DeviceRename sets a nickname for a device
 func (c *Client) DeviceRename(address string, nickname string) error {
-	result, err := c.executeCommand(fmt.Sprintf("DEVICE RENAME,%s,%s", address, nickname))
+	return c.DeviceRenameContext(context.Background(), address, nickname)
+}
+
+// DeviceRenameContext is like DeviceRename but uses ctx to cancel or time out the command
+func (c *Client) DeviceRenameContext(ctx context.Context, address string, nickname string) error {
+	result, err := c.execute(ctx, "DEVICE RENAME", address, nickname)
 	if err != nil {
 		return err
 	}
@@ -151,7 +182,12 @@ func (c *Client) DeviceRename(address string, nickname string) error {
 
 // ServerRename renames a server
 func (c *Client) ServerRename(hubAddress string, newName string) error {
-	result, err := c.executeCommand(fmt.Sprintf("SERVER RENAME,%s,%s", hubAddress, newName))
+	return c.ServerRenameContext(context.Background(), hubAddress, newName)
+}
+
+// ServerRenameContext is like ServerRename but uses ctx to cancel or time out the command
+func (c *Client) ServerRenameContext(ctx context.Context, hubAddress string, newName string) error {
+	result, err := c.execute(ctx, "SERVER RENAME", hubAddress, newName)
 	if err != nil {
 		return err
 	}
@@ -163,9 +199,15 @@ func (c *Client) ServerRename(hubAddress string, newName string) error {
 	return nil
 }
 
-// AutoUseAll turns auto-use all devices on
+// AutoUseAll toggles auto-use of all devices. SetAutoUseAll sets it
+// idempotently.
 func (c *Client) AutoUseAll() error {
-	result, err := c.executeCommand("AUTO USE ALL")
+	return c.AutoUseAllContext(context.Background())
+}
+
+// AutoUseAllContext is like AutoUseAll but uses ctx to cancel or time out the command
+func (c *Client) AutoUseAllContext(ctx context.Context) error {
+	result, err := c.execute(ctx, "AUTO USE ALL")
 	if err != nil {
 		return err
 	}
@@ -179,7 +221,12 @@ func (c *Client) AutoUseAll() error {
 
 // AutoUseHub toggles auto-use for all devices on a specific hub
 func (c *Client) AutoUseHub(serverName string) error {
-	result, err := c.executeCommand(fmt.Sprintf("AUTO USE HUB,%s", serverName))
+	return c.AutoUseHubContext(context.Background(), serverName)
+}
+
+// AutoUseHubContext is like AutoUseHub but uses ctx to cancel or time out the command
+func (c *Client) AutoUseHubContext(ctx context.Context, serverName string) error {
+	result, err := c.execute(ctx, "AUTO USE HUB", serverName)
 	if err != nil {
 		return err
 	}
@@ -193,7 +240,12 @@ func (c *Client) AutoUseHub(serverName string) error {
 
 // AutoUsePort toggles auto-use for any device on a specific port
 func (c *Client) AutoUsePort(address string) error {
-	result, err := c.executeCommand(fmt.Sprintf("AUTO USE PORT,%s", address))
+	return c.AutoUsePortContext(context.Background(), address)
+}
+
+// AutoUsePortContext is like AutoUsePort but uses ctx to cancel or time out the command
+func (c *Client) AutoUsePortContext(ctx context.Context, address string) error {
+	result, err := c.execute(ctx, "AUTO USE PORT", address)
 	if err != nil {
 		return err
 	}
@@ -207,7 +259,12 @@ func (c *Client) AutoUsePort(address string) error {
 
 // AutoUseDevice toggles auto-use for a specific device on any port
 func (c *Client) AutoUseDevice(address string) error {
-	result, err := c.executeCommand(fmt.Sprintf("AUTO USE DEVICE,%s", address))
+	return c.AutoUseDeviceContext(context.Background(), address)
+}
+
+// AutoUseDeviceContext is like AutoUseDevice but uses ctx to cancel or time out the command
+func (c *Client) AutoUseDeviceContext(ctx context.Context, address string) error {
+	result, err := c.execute(ctx, "AUTO USE DEVICE", address)
 	if err != nil {
 		return err
 	}
@@ -221,7 +278,12 @@ func (c *Client) AutoUseDevice(address string) error {
 
 // AutoUseDevicePort toggles auto-use for a specific device on a specific port
 func (c *Client) AutoUseDevicePort(address string) error {
-	result, err := c.executeCommand(fmt.Sprintf("AUTO USE DEVICE PORT,%s", address))
+	return c.AutoUseDevicePortContext(context.Background(), address)
+}
+
+// AutoUseDevicePortContext is like AutoUseDevicePort but uses ctx to cancel or time out the command
+func (c *Client) AutoUseDevicePortContext(ctx context.Context, address string) error {
+	result, err := c.execute(ctx, "AUTO USE DEVICE PORT", address)
 	if err != nil {
 		return err
 	}
@@ -235,7 +297,12 @@ func (c *Client) AutoUseDevicePort(address string) error {
 
 // AutoUseClearAll clears all auto-use settings
 func (c *Client) AutoUseClearAll() error {
-	result, err := c.executeCommand("AUTO USE CLEAR ALL")
+	return c.AutoUseClearAllContext(context.Background())
+}
+
+// AutoUseClearAllContext is like AutoUseClearAll but uses ctx to cancel or time out the command
+func (c *Client) AutoUseClearAllContext(ctx context.Context) error {
+	result, err := c.execute(ctx, "AUTO USE CLEAR ALL")
 	if err != nil {
 		return err
 	}
@@ -250,7 +317,12 @@ func (c *Client) AutoUseClearAll() error {
 // ManualHubAdd adds a manually specified hub to connect to
 // address can be in format "address:port" or "EasyFind address"
 func (c *Client) ManualHubAdd(address string) error {
-	result, err := c.executeCommand(fmt.Sprintf("MANUAL HUB ADD,%s", address))
+	return c.ManualHubAddContext(context.Background(), address)
+}
+
+// ManualHubAddContext is like ManualHubAdd but uses ctx to cancel or time out the command
+func (c *Client) ManualHubAddContext(ctx context.Context, address string) error {
+	result, err := c.execute(ctx, "MANUAL HUB ADD", address)
 	if err != nil {
 		return err
 	}
@@ -264,7 +336,12 @@ func (c *Client) ManualHubAdd(address string) error {
 
 // ManualHubRemove removes a manually specified hub
 func (c *Client) ManualHubRemove(address string) error {
-	result, err := c.executeCommand(fmt.Sprintf("MANUAL HUB REMOVE,%s", address))
+	return c.ManualHubRemoveContext(context.Background(), address)
+}
+
+// ManualHubRemoveContext is like ManualHubRemove but uses ctx to cancel or time out the command
+func (c *Client) ManualHubRemoveContext(ctx context.Context, address string) error {
+	result, err := c.execute(ctx, "MANUAL HUB REMOVE", address)
 	if err != nil {
 		return err
 	}
@@ -278,7 +355,12 @@ func (c *Client) ManualHubRemove(address string) error {
 
 // ManualHubRemoveAll removes all manually specified hubs
 func (c *Client) ManualHubRemoveAll() error {
-	result, err := c.executeCommand("MANUAL HUB REMOVE ALL")
+	return c.ManualHubRemoveAllContext(context.Background())
+}
+
+// ManualHubRemoveAllContext is like ManualHubRemoveAll but uses ctx to cancel or time out the command
+func (c *Client) ManualHubRemoveAllContext(ctx context.Context) error {
+	result, err := c.execute(ctx, "MANUAL HUB REMOVE ALL")
 	if err != nil {
 		return err
 	}
@@ -292,7 +374,12 @@ func (c *Client) ManualHubRemoveAll() error {
 
 // ManualHubList returns a list of manually specified hubs
 func (c *Client) ManualHubList() ([]string, error) {
-	result, err := c.executeCommand("MANUAL HUB LIST")
+	return c.ManualHubListContext(context.Background())
+}
+
+// ManualHubListContext is like ManualHubList but uses ctx to cancel or time out the command
+func (c *Client) ManualHubListContext(ctx context.Context) ([]string, error) {
+	result, err := c.execute(ctx, "MANUAL HUB LIST")
 	if err != nil {
 		return nil, err
 	}
@@ -316,7 +403,12 @@ func (c *Client) ManualHubList() ([]string, error) {
 
 // AddReverse adds a reverse client to the server
 func (c *Client) AddReverse(serverSerial string, clientAddress string) error {
-	result, err := c.executeCommand(fmt.Sprintf("ADD REVERSE,%s,%s", serverSerial, clientAddress))
+	return c.AddReverseContext(context.Background(), serverSerial, clientAddress)
+}
+
+// AddReverseContext is like AddReverse but uses ctx to cancel or time out the command
+func (c *Client) AddReverseContext(ctx context.Context, serverSerial string, clientAddress string) error {
+	result, err := c.execute(ctx, "ADD REVERSE", serverSerial, clientAddress)
 	if err != nil {
 		return err
 	}
@@ -330,7 +422,12 @@ func (c *Client) AddReverse(serverSerial string, clientAddress string) error {
 
 // RemoveReverse removes a reverse client from the server
 func (c *Client) RemoveReverse(serverSerial string, clientAddress string) error {
-	result, err := c.executeCommand(fmt.Sprintf("REMOVE REVERSE,%s,%s", serverSerial, clientAddress))
+	return c.RemoveReverseContext(context.Background(), serverSerial, clientAddress)
+}
+
+// RemoveReverseContext is like RemoveReverse but uses ctx to cancel or time out the command
+func (c *Client) RemoveReverseContext(ctx context.Context, serverSerial string, clientAddress string) error {
+	result, err := c.execute(ctx, "REMOVE REVERSE", serverSerial, clientAddress)
 	if err != nil {
 		return err
 	}
@@ -344,7 +441,12 @@ func (c *Client) RemoveReverse(serverSerial string, clientAddress string) error
 
 // ListReverse lists all reverse clients for a server
 func (c *Client) ListReverse(serverSerial string) ([]string, error) {
-	result, err := c.executeCommand(fmt.Sprintf("LIST REVERSE,%s", serverSerial))
+	return c.ListReverseContext(context.Background(), serverSerial)
+}
+
+// ListReverseContext is like ListReverse but uses ctx to cancel or time out the command
+func (c *Client) ListReverseContext(ctx context.Context, serverSerial string) ([]string, error) {
+	result, err := c.execute(ctx, "LIST REVERSE", serverSerial)
 	if err != nil {
 		return nil, err
 	}
@@ -366,9 +468,33 @@ func (c *Client) ListReverse(serverSerial string) ([]string, error) {
 	return clients, nil
 }
 
+// ReverseClients returns the reverse clients configured for a server
+func (c *Client) ReverseClients(serverSerial string) ([]ReverseClient, error) {
+	return c.ReverseClientsContext(context.Background(), serverSerial)
+}
+
+// ReverseClientsContext is like ReverseClients but uses ctx to cancel or time out the command
+func (c *Client) ReverseClientsContext(ctx context.Context, serverSerial string) ([]ReverseClient, error) {
+	result, err := c.execute(ctx, "LIST REVERSE", serverSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Success {
+		return nil, result.Error
+	}
+
+	return parseReverseClients(serverSerial, result.Output)
+}
+
 // ListLicenses returns a list of licenses
 func (c *Client) ListLicenses() ([]string, error) {
-	result, err := c.executeCommand("LIST LICENSES")
+	return c.ListLicensesContext(context.Background())
+}
+
+// ListLicensesContext is like ListLicenses but uses ctx to cancel or time out the command
+func (c *Client) ListLicensesContext(ctx context.Context) ([]string, error) {
+	result, err := c.execute(ctx, "LIST LICENSES")
 	if err != nil {
 		return nil, err
 	}
@@ -390,9 +516,34 @@ func (c *Client) ListLicenses() ([]string, error) {
 	return licenses, nil
 }
 
+// Licenses returns the licenses known to the client, with the server serial
+// and device count decoded from each key
+func (c *Client) Licenses() ([]License, error) {
+	return c.LicensesContext(context.Background())
+}
+
+// LicensesContext is like Licenses but uses ctx to cancel or time out the command
+func (c *Client) LicensesContext(ctx context.Context) ([]License, error) {
+	result, err := c.execute(ctx, "LIST LICENSES")
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Success {
+		return nil, result.Error
+	}
+
+	return parseLicenses(result.Output, c.strict)
+}
+
 // LicenseServer licenses a server with a license key
 func (c *Client) LicenseServer(licenseKey string) error {
-	result, err := c.executeCommand(fmt.Sprintf("LICENSE SERVER,%s", licenseKey))
+	return c.LicenseServerContext(context.Background(), licenseKey)
+}
+
+// LicenseServerContext is like LicenseServer but uses ctx to cancel or time out the command
+func (c *Client) LicenseServerContext(ctx context.Context, licenseKey string) error {
+	result, err := c.execute(ctx, "LICENSE SERVER", licenseKey)
 	if err != nil {
 		return err
 	}
@@ -406,7 +557,12 @@ func (c *Client) LicenseServer(licenseKey string) error {
 
 // ClearLog clears the client log
 func (c *Client) ClearLog() error {
-	result, err := c.executeCommand("CLEAR LOG")
+	return c.ClearLogContext(context.Background())
+}
+
+// ClearLogContext is like ClearLog but uses ctx to cancel or time out the command
+func (c *Client) ClearLogContext(ctx context.Context) error {
+	result, err := c.execute(ctx, "CLEAR LOG")
 	if err != nil {
 		return err
 	}
@@ -420,7 +576,12 @@ func (c *Client) ClearLog() error {
 
 // CustomEvent sets a custom device event
 func (c *Client) CustomEvent(address string, event string) error {
-	result, err := c.executeCommand(fmt.Sprintf("CUSTOM EVENT,%s,%s", address, event))
+	return c.CustomEventContext(context.Background(), address, event)
+}
+
+// CustomEventContext is like CustomEvent but uses ctx to cancel or time out the command
+func (c *Client) CustomEventContext(ctx context.Context, address string, event string) error {
+	result, err := c.execute(ctx, "CUSTOM EVENT", address, event)
 	if err != nil {
 		return err
 	}
@@ -434,7 +595,12 @@ func (c *Client) CustomEvent(address string, event string) error {
 
 // AutoFind toggles auto-find functionality
 func (c *Client) AutoFind() error {
-	result, err := c.executeCommand("AUTOFIND")
+	return c.AutoFindContext(context.Background())
+}
+
+// AutoFindContext is like AutoFind but uses ctx to cancel or time out the command
+func (c *Client) AutoFindContext(ctx context.Context) error {
+	result, err := c.execute(ctx, "AUTOFIND")
 	if err != nil {
 		return err
 	}
@@ -448,7 +614,12 @@ func (c *Client) AutoFind() error {
 
 // Reverse toggles reverse lookup functionality
 func (c *Client) Reverse() error {
-	result, err := c.executeCommand("REVERSE")
+	return c.ReverseContext(context.Background())
+}
+
+// ReverseContext is like Reverse but uses ctx to cancel or time out the command
+func (c *Client) ReverseContext(ctx context.Context) error {
+	result, err := c.execute(ctx, "REVERSE")
 	if err != nil {
 		return err
 	}
@@ -462,7 +633,12 @@ func (c *Client) Reverse() error {
 
 // SSLReverse toggles reverse SSL lookup
 func (c *Client) SSLReverse() error {
-	result, err := c.executeCommand("SSLREVERSE")
+	return c.SSLReverseContext(context.Background())
+}
+
+// SSLReverseContext is like SSLReverse but uses ctx to cancel or time out the command
+func (c *Client) SSLReverseContext(ctx context.Context) error {
+	result, err := c.execute(ctx, "SSLREVERSE")
 	if err != nil {
 		return err
 	}
@@ -476,7 +652,12 @@ func (c *Client) SSLReverse() error {
 
 // Exit shuts down the client
 func (c *Client) Exit() error {
-	result, err := c.executeCommand("EXIT")
+	return c.ExitContext(context.Background())
+}
+
+// ExitContext is like Exit but uses ctx to cancel or time out the command
+func (c *Client) ExitContext(ctx context.Context) error {
+	result, err := c.execute(ctx, "EXIT")
 	if err != nil {
 		return err
 	}
@@ -490,7 +671,12 @@ func (c *Client) Exit() error {
 
 // Help returns the help message with available commands
 func (c *Client) Help() (string, error) {
-	result, err := c.executeCommand("HELP")
+	return c.HelpContext(context.Background())
+}
+
+// HelpContext is like Help but uses ctx to cancel or time out the command
+func (c *Client) HelpContext(ctx context.Context) (string, error) {
+	result, err := c.execute(ctx, "HELP")
 	if err != nil {
 		return "", err
 	}
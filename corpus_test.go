@@ -0,0 +1,220 @@
+package virtualhere
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata/corpus from the parsers")
+
+// The corpus in testdata/corpus holds sample outputs of VirtualHere client
+// commands. The samples are synthetic: they were written by hand after the
+// documented output formats, not captured from a running daemon, and are not
+// tied to any client version. Samples live in <kind>/<name>.txt, each
+// possibly accompanied by:
+//
+//   - <name>.json, the result of the lenient parser encoded as JSON; absent if
+//     lenient parsing fails too
+//   - <name>.strict, text contained in the error returned in strict mode;
+//     absent if strict parsing succeeds
+//
+// Kinds are named after the command and map to their parser below.
+var corpusParsers = map[string]func(output string, strict bool) (any, error){
+	"list": func(output string, strict bool) (any, error) {
+		return parseListOutput(output, strict)
+	},
+	"device_info": func(output string, strict bool) (any, error) {
+		return parseDeviceInfo(output, strict)
+	},
+	"server_info": func(output string, strict bool) (any, error) {
+		return parseServerInfo(output, strict)
+	},
+	"client_state": func(output string, strict bool) (any, error) {
+		return parseClientStateXML(output, strict)
+	},
+	"list_licenses": func(output string, strict bool) (any, error) {
+		return parseLicenses(output, strict)
+	},
+}
+
+// corpusSample is one command output from testdata/corpus
+type corpusSample struct {
+	name        string // File name without extension, e.g. "in_use"
+	base        string // File path without extension
+	output      string // Raw output as returned by the daemon
+	golden      []byte // Expected lenient parse result as JSON, nil if parsing fails
+	strictError string // Expected strict mode error text, empty if strict parsing succeeds
+}
+
+// loadCorpus returns the samples of kind, sorted by name
+func loadCorpus(tb testing.TB, kind string) []corpusSample {
+	tb.Helper()
+	root := filepath.Join("testdata", "corpus", kind)
+
+	var samples []corpusSample
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		base, ok := strings.CutSuffix(file, ".txt")
+		if !ok || entry.IsDir() {
+			return nil
+		}
+
+		name, _ := filepath.Rel(root, base)
+		sample := corpusSample{name: filepath.ToSlash(name), base: base}
+
+		output, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		sample.output = string(output)
+
+		if sample.golden, err = readOptional(base + ".json"); err != nil {
+			return err
+		}
+		strictError, err := readOptional(base + ".strict")
+		if err != nil {
+			return err
+		}
+		sample.strictError = strings.TrimSpace(string(strictError))
+
+		samples = append(samples, sample)
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("reading corpus %q: %v", kind, err)
+	}
+	if len(samples) == 0 {
+		tb.Fatalf("corpus %q has no samples", kind)
+	}
+	return samples
+}
+
+// readOptional reads a corpus file, returning nil if it does not exist
+func readOptional(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func TestCorpusKinds(t *testing.T) {
+	entries, err := os.ReadDir(filepath.Join("testdata", "corpus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if _, ok := corpusParsers[entry.Name()]; !ok {
+			t.Errorf("corpus kind %q has no parser", entry.Name())
+		}
+	}
+}
+
+func TestCorpus(t *testing.T) {
+	for _, kind := range slices.Sorted(maps.Keys(corpusParsers)) {
+		parse := corpusParsers[kind]
+		for _, sample := range loadCorpus(t, kind) {
+			t.Run(kind+"/"+sample.name, func(t *testing.T) {
+				if *update {
+					updateGolden(t, sample, parse)
+					return
+				}
+
+				got, err := parse(sample.output, false)
+				switch {
+				case sample.golden == nil && err == nil:
+					t.Errorf("lenient parsing succeeded, want an error as there is no golden file")
+				case sample.golden != nil && err != nil:
+					t.Errorf("lenient parsing: %v", err)
+				case sample.golden != nil:
+					assertJSONEqual(t, got, sample.golden)
+				}
+
+				_, err = parse(sample.output, true)
+				switch {
+				case sample.strictError == "" && err != nil:
+					t.Errorf("strict parsing: %v", err)
+				case sample.strictError != "" && err == nil:
+					t.Errorf("strict parsing succeeded, want an error containing %q", sample.strictError)
+				case sample.strictError != "":
+					if !errors.Is(err, ErrInvalidResponse) || !strings.Contains(err.Error(), sample.strictError) {
+						t.Errorf("strict parsing returned %v, want ErrInvalidResponse containing %q", err, sample.strictError)
+					}
+				}
+			})
+		}
+	}
+}
+
+// assertJSONEqual compares the JSON encoding of got with a golden file,
+// ignoring formatting
+func assertJSONEqual(t *testing.T, got any, golden []byte) {
+	t.Helper()
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotValue, wantValue any
+	if err := json.Unmarshal(data, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(golden, &wantValue); err != nil {
+		t.Fatalf("malformed golden file: %v", err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("lenient result differs from golden file\n got %s\nwant %s", data, bytes.TrimSpace(golden))
+	}
+}
+
+// updateGolden rewrites the golden files of sample from the parser's results
+func updateGolden(t *testing.T, sample corpusSample, parse func(string, bool) (any, error)) {
+	t.Helper()
+
+	got, err := parse(sample.output, false)
+	if err == nil {
+		data, err := json.MarshalIndent(got, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeOrRemove(t, sample.base+".json", append(data, '\n'))
+	} else {
+		writeOrRemove(t, sample.base+".json", nil)
+	}
+
+	if _, err := parse(sample.output, true); err != nil {
+		message := strings.TrimPrefix(err.Error(), ErrInvalidResponse.Error()+": ")
+		writeOrRemove(t, sample.base+".strict", []byte(message+"\n"))
+	} else {
+		writeOrRemove(t, sample.base+".strict", nil)
+	}
+}
+
+// writeOrRemove writes data to file, or removes file if data is nil
+func writeOrRemove(t *testing.T, file string, data []byte) {
+	t.Helper()
+	var err error
+	if data == nil {
+		err = os.Remove(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	} else {
+		err = os.WriteFile(file, data, 0o644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,175 @@
+package virtualhere
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+// RemoteDevice is the unified view of a USB device shared by a VirtualHere
+// server. It merges what LIST and GET CLIENT STATE report about the device.
+type RemoteDevice struct {
+	Address           DeviceAddress `json:"address"`            // e.g. raspberrypi.114
+	Name              string        `json:"name"`               // Nickname if set, otherwise the product name
+	Vendor            string        `json:"vendor"`             // e.g. "FTDI"
+	Product           string        `json:"product"`            // e.g. "FT232R USB UART"
+	VendorID          uint16        `json:"vendor_id"`          // e.g. 0x0403
+	ProductID         uint16        `json:"product_id"`         // e.g. 0x6001
+	Serial            string        `json:"serial"`             // Device serial number
+	Nickname          string        `json:"nickname"`           // Custom nickname, empty if none
+	AutoUse           AutoUseMode   `json:"auto_use"`           // Auto-use mode, e.g. "not-set"
+	State             DeviceState   `json:"state"`              // Device state as reported by GET CLIENT STATE
+	HolderHostname    string        `json:"holder_hostname"`    // Hostname of the client using the device
+	HolderIP          string        `json:"holder_ip"`          // IP address of the client using the device
+	Hub               HubRef        `json:"hub"`                // Server the device is attached to
+	InterfaceClass    int           `json:"interface_class"`    // USB class of the first interface
+	InterfaceSubClass int           `json:"interface_subclass"` // USB subclass of the first interface
+	InterfaceProtocol int           `json:"interface_protocol"` // USB protocol of the first interface
+	ParentHubPort     int           `json:"parent_hub_port"`    // Port on the parent USB hub
+	FromList          bool          `json:"from_list"`          // Built from LIST only: IDs, serial and holder IP are unknown
+}
+
+// HubRef identifies the VirtualHere server a device is attached to
+type HubRef struct {
+	Name     string     `json:"name"`     // e.g. "Raspberry Hub"
+	Address  HubAddress `json:"address"`  // e.g. raspberrypi:7575
+	Hostname string     `json:"hostname"` // Prefix of device addresses, e.g. "raspberrypi"
+	Serial   string     `json:"serial"`   // Server serial number
+}
+
+// Devices returns every device of every connected server, built from
+// GET CLIENT STATE. If the client cannot provide its state (for example an
+// older client rejecting the command or returning malformed XML), the devices
+// are built from LIST instead and have FromList set.
+func (c *Client) Devices() ([]RemoteDevice, error) {
+	return c.DevicesContext(context.Background())
+}
+
+// DevicesContext is like Devices but uses ctx to cancel or time out the commands
+func (c *Client) DevicesContext(ctx context.Context) ([]RemoteDevice, error) {
+	state, err := c.GetClientStateContext(ctx)
+	if err == nil {
+		return state.Devices(), nil
+	}
+
+	var cmdErr *CommandError
+	var syntaxErr *xml.SyntaxError
+	if !errors.As(err, &cmdErr) && !errors.As(err, &syntaxErr) && !errors.Is(err, ErrInvalidResponse) {
+		return nil, err
+	}
+
+	list, listErr := c.ListContext(ctx)
+	if listErr != nil {
+		return nil, errors.Join(err, listErr)
+	}
+	return list.Devices(), nil
+}
+
+// Devices returns the devices of every server in the state
+func (s *XMLClientState) Devices() []RemoteDevice {
+	devices := make([]RemoteDevice, 0)
+	for i := range s.Servers {
+		server := &s.Servers[i]
+		hub := server.hubRef()
+		for j := range server.Devices {
+			devices = append(devices, server.Devices[j].remoteDevice(hub))
+		}
+	}
+	return devices
+}
+
+// hubRef returns the reference devices of this server link to
+func (s *XMLServer) hubRef() HubRef {
+	conn := &s.Connection
+	ref := HubRef{
+		Name:     conn.ServerName,
+		Hostname: conn.Hostname,
+		Serial:   conn.ServerSerial,
+	}
+
+	if conn.Host != "" {
+		ref.Address = HubAddress{Host: conn.Host, Port: conn.Port}
+		ref.Address.EasyFind = conn.Port == 0 && isEasyFindHost(conn.Host)
+	}
+	if ref.Hostname == "" {
+		ref.Hostname = conn.Host
+	}
+	return ref
+}
+
+// remoteDevice converts the XML device into the unified model
+func (d *XMLDevice) remoteDevice(hub HubRef) RemoteDevice {
+	device := RemoteDevice{
+		Address:           DeviceAddress{Hub: hub.Hostname, Address: d.Address},
+		Name:              d.Product,
+		Vendor:            d.Vendor,
+		Product:           d.Product,
+		VendorID:          uint16(d.IDVendor),
+		ProductID:         uint16(d.IDProduct),
+		Serial:            d.DeviceSerial,
+		Nickname:          d.Nickname,
+		AutoUse:           d.AutoUse,
+		State:             d.State,
+		HolderHostname:    d.BoundClientHostname,
+		HolderIP:          d.BoundConnectionIP,
+		Hub:               hub,
+		InterfaceClass:    d.FirstInterfaceClass,
+		InterfaceSubClass: d.FirstInterfaceSubClass,
+		InterfaceProtocol: d.FirstInterfaceProtocol,
+		ParentHubPort:     d.ParentHubPort,
+	}
+
+	if d.Nickname != "" {
+		device.Name = d.Nickname
+	}
+	if device.HolderIP == "" {
+		device.HolderIP = d.BoundConnectionIP6
+	}
+	return device
+}
+
+// Devices returns the devices of every hub in the LIST state. Only the
+// address, names, state, holder and auto-use flag are known for them.
+func (s *ClientState) Devices() []RemoteDevice {
+	devices := make([]RemoteDevice, 0)
+	for _, hub := range s.Hubs {
+		ref := HubRef{Name: hub.Name}
+		ref.Address, _ = hub.ParsedAddress()
+
+		for _, d := range hub.Devices {
+			device := RemoteDevice{
+				Name:     d.Name,
+				Product:  d.Name,
+				Nickname: d.Nickname,
+				Hub:      ref,
+				State:    DeviceAvailable,
+				FromList: true,
+			}
+			if address, err := d.ParsedAddress(); err == nil {
+				device.Address = address
+				device.Hub.Hostname = address.Hub
+			}
+			if d.Nickname != "" {
+				device.Name = d.Nickname
+			}
+			if d.AutoUse {
+				device.AutoUse = AutoUseOn
+			}
+			switch {
+			case d.InUseByMe:
+				device.State = DeviceInUseByMe
+			case d.InUse:
+				device.State = DeviceInUseByOther
+				device.HolderHostname = d.Holder
+			}
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
+// IDString returns the vendor and product IDs as "vvvv:pppp" in hexadecimal
+func (d RemoteDevice) IDString() string {
+	return fmt.Sprintf("%04x:%04x", d.VendorID, d.ProductID)
+}
@@ -0,0 +1,70 @@
+package virtualhere
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SocketDirEnv names the environment variable consulted for the directory
+// holding the vhclient and vhclient_response sockets when no socket option is given
+const SocketDirEnv = "VHCLIENT_SOCKET_DIR"
+
+// Socket file names created by the VirtualHere client inside its socket directory
+const (
+	requestSocketName  = "vhclient"
+	responseSocketName = "vhclient_response"
+)
+
+// WithSocketPaths points the client at alternative request/response Unix
+// socket paths, e.g. when the daemon's sockets are bind-mounted into a container.
+// An empty path keeps the default for that socket.
+func WithSocketPaths(requestPath, responsePath string) ClientOption {
+	return func(c *Client) {
+		c.requestPath = requestPath
+		c.responsePath = responsePath
+	}
+}
+
+// WithSocketDir points the client at the vhclient and vhclient_response sockets
+// inside dir instead of /tmp
+func WithSocketDir(dir string) ClientOption {
+	return WithSocketPaths(
+		filepath.Join(dir, requestSocketName),
+		filepath.Join(dir, responseSocketName),
+	)
+}
+
+// resolveSocketPaths fills in the socket paths from SocketDirEnv when neither
+// was configured through an option. It reports whether any path was configured.
+func (c *Client) resolveSocketPaths() bool {
+	if c.requestPath == "" && c.responsePath == "" {
+		if dir := os.Getenv(SocketDirEnv); dir != "" {
+			WithSocketDir(dir)(c)
+		}
+	}
+	return c.requestPath != "" || c.responsePath != ""
+}
+
+// validateSocketTransport checks that both sockets of transport exist
+func validateSocketTransport(transport *UnixSocketTransport) error {
+	if err := checkSocket("request", transport.requestPath()); err != nil {
+		return err
+	}
+	return checkSocket("response", transport.responsePath())
+}
+
+// checkSocket returns an error wrapping ErrSocketNotFound if path is missing
+// or is not a Unix domain socket
+func checkSocket(kind string, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%w: %s socket %s: %v", ErrSocketNotFound, kind, path, err)
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%w: %s socket %s is not a socket", ErrSocketNotFound, kind, path)
+	}
+
+	return nil
+}
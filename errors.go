@@ -0,0 +1,141 @@
+package virtualhere
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorKind classifies why the VirtualHere client rejected a command
+type ErrorKind int
+
+const (
+	KindFailed         ErrorKind = iota // FAILED, or an ERROR that matches no other kind
+	KindDeviceInUse                     // The device is already in use
+	KindDeviceNotFound                  // No device with the given address
+	KindServerNotFound                  // No server/hub with the given name or address
+	KindInvalidAddress                  // The address argument is malformed
+)
+
+// String returns a short description of the kind
+func (k ErrorKind) String() string {
+	switch k {
+	case KindDeviceInUse:
+		return "device in use"
+	case KindDeviceNotFound:
+		return "device not found"
+	case KindServerNotFound:
+		return "server not found"
+	case KindInvalidAddress:
+		return "invalid address"
+	default:
+		return "failed"
+	}
+}
+
+// sentinel returns the package error corresponding to the kind
+func (k ErrorKind) sentinel() error {
+	switch k {
+	case KindDeviceInUse:
+		return ErrDeviceInUse
+	case KindDeviceNotFound:
+		return ErrDeviceNotFound
+	case KindServerNotFound:
+		return ErrServerNotFound
+	case KindInvalidAddress:
+		return ErrInvalidAddress
+	default:
+		return ErrCommandFailed
+	}
+}
+
+// CommandError is returned when the VirtualHere client answers a command with
+// FAILED or ERROR. Every CommandError matches ErrCommandFailed with errors.Is;
+// it also matches the sentinel of its Kind, e.g. ErrDeviceInUse.
+type CommandError struct {
+	Command string    // Command that was sent, with passwords and license keys redacted
+	Output  string    // Raw response, e.g. "FAILED" or "ERROR: device in use"
+	Kind    ErrorKind // Classification of Output
+}
+
+// newCommandError classifies a FAILED or ERROR response to cmd
+func newCommandError(cmd Command, output string) *CommandError {
+	return &CommandError{
+		Command: cmd.Redacted(),
+		Output:  redactOutput(cmd, output),
+		Kind:    classifyError(output),
+	}
+}
+
+// Message returns the text after "ERROR:", or "command failed" for FAILED
+func (e *CommandError) Message() string {
+	if message, ok := strings.CutPrefix(e.Output, "ERROR:"); ok {
+		return strings.TrimSpace(message)
+	}
+	return ErrCommandFailed.Error()
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Command, e.Message())
+}
+
+// Is reports whether target is ErrCommandFailed or the sentinel of e.Kind
+func (e *CommandError) Is(target error) bool {
+	return target == ErrCommandFailed || target == e.Kind.sentinel()
+}
+
+// secretError is an error whose text had the secret arguments of a command
+// removed, e.g. a transport error quoting the command line. The original
+// error remains reachable with errors.Is and errors.As.
+type secretError struct {
+	err  error
+	text string
+}
+
+func (e *secretError) Error() string {
+	return e.text
+}
+
+func (e *secretError) Unwrap() error {
+	return e.err
+}
+
+// redactError removes the secret arguments of cmd from the text of err
+func redactError(cmd Command, err error) error {
+	text := redactOutput(cmd, err.Error())
+	if text == err.Error() {
+		return err
+	}
+	return &secretError{err: err, text: text}
+}
+
+// classifyError maps the text of a daemon error response to an ErrorKind.
+// Phrases are matched whole, and negated forms such as "device not in use"
+// (the reply to STOP USING for a free device) are checked first.
+func classifyError(output string) ErrorKind {
+	text := strings.ToLower(output)
+
+	switch {
+	case containsAny(text, "not in use", "not in-use", "not being used"):
+		return KindFailed
+	case containsAny(text, "already in use", "in use by", "in-use by", "device in use", "device is in use"):
+		return KindDeviceInUse
+	case containsAny(text, "invalid address", "bad address"):
+		return KindInvalidAddress
+	case containsAny(text, "server not found", "hub not found", "no such server", "unknown server"):
+		return KindServerNotFound
+	case containsAny(text, "device not found", "no such device", "unknown device"):
+		return KindDeviceNotFound
+	default:
+		return KindFailed
+	}
+}
+
+// containsAny reports whether s contains any of the phrases
+func containsAny(s string, phrases ...string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(s, phrase) {
+			return true
+		}
+	}
+	return false
+}
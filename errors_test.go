@@ -0,0 +1,61 @@
+package virtualhere
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		output string
+		want   ErrorKind
+	}{
+		{"FAILED", KindFailed},
+		{"ERROR: device in use", KindDeviceInUse},
+		{"ERROR: device in use by bob-pc", KindDeviceInUse},
+		{"ERROR: Device is already in use", KindDeviceInUse},
+		{"ERROR: In-use by alice (alice-laptop)", KindDeviceInUse},
+		{"ERROR: device not in use", KindFailed},
+		{"ERROR: Device is not in use by you", KindFailed},
+		{"ERROR: device not in-use", KindFailed},
+		{"ERROR: device not found", KindDeviceNotFound},
+		{"ERROR: no such device raspberrypi.114", KindDeviceNotFound},
+		{"ERROR: unknown device", KindDeviceNotFound},
+		{"ERROR: server not found", KindServerNotFound},
+		{"ERROR: Hub not found", KindServerNotFound},
+		{"ERROR: no such server", KindServerNotFound},
+		{"ERROR: invalid address", KindInvalidAddress},
+		{"ERROR: bad address format", KindInvalidAddress},
+		{"ERROR: not found", KindFailed},
+		{"ERROR: license file not found", KindFailed},
+		{"ERROR: invalid arguments", KindFailed},
+		{"ERROR: unknown command", KindFailed},
+	}
+
+	for _, tt := range tests {
+		if got := classifyError(tt.output); got != tt.want {
+			t.Errorf("classifyError(%q) = %s, want %s", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestCommandErrorIs(t *testing.T) {
+	stop := Command{Verb: "STOP USING", Args: []string{"raspberrypi.114"}}
+
+	err := error(newCommandError(stop, "ERROR: device not in use"))
+	if errors.Is(err, ErrDeviceInUse) {
+		t.Errorf("%v matches ErrDeviceInUse", err)
+	}
+	if !errors.Is(err, ErrCommandFailed) {
+		t.Errorf("%v does not match ErrCommandFailed", err)
+	}
+
+	use := Command{Verb: "USE", Args: []string{"raspberrypi.114", "secret"}}
+	err = newCommandError(use, "ERROR: device in use by bob")
+	if !errors.Is(err, ErrDeviceInUse) || !errors.Is(err, ErrCommandFailed) || errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("%v does not match exactly ErrDeviceInUse and ErrCommandFailed", err)
+	}
+	if want := "USE,raspberrypi.114,<redacted>: device in use by bob"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
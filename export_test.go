@@ -0,0 +1,8 @@
+package virtualhere
+
+// QueueLen reports how many commands are waiting for the IPC channel of c
+func QueueLen(c *Client) int {
+	c.queue.mu.Lock()
+	defer c.queue.mu.Unlock()
+	return c.queue.waiters.Len()
+}
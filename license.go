@@ -0,0 +1,56 @@
+package virtualhere
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LicenseKey is a decoded VirtualHere server license key of the form
+// "<server serial>,<max devices>,<signature>"
+type LicenseKey struct {
+	ServerSerial string // Serial of the server the key is for
+	MaxDevices   int    // Licensed device count, or UnlimitedDevices
+	Signature    string // Opaque signature, kept as given
+}
+
+// ParseLicenseKey decodes a license key. A device count of 0 means unlimited.
+// Errors match ErrInvalidLicense and never include the signature.
+func ParseLicenseKey(key string) (LicenseKey, error) {
+	parts := strings.SplitN(strings.TrimSpace(key), ",", 3)
+	if len(parts) != 3 {
+		return LicenseKey{}, fmt.Errorf("%w: expected <server serial>,<max devices>,<signature>", ErrInvalidLicense)
+	}
+
+	serial, count, signature := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), strings.TrimSpace(parts[2])
+	if serial == "" {
+		return LicenseKey{}, fmt.Errorf("%w: empty server serial", ErrInvalidLicense)
+	}
+	if signature == "" {
+		return LicenseKey{}, fmt.Errorf("%w: empty signature", ErrInvalidLicense)
+	}
+
+	maxDevices, err := strconv.Atoi(count)
+	if err != nil || maxDevices < 0 {
+		return LicenseKey{}, fmt.Errorf("%w: device count %q is not a number", ErrInvalidLicense, count)
+	}
+	if maxDevices == 0 {
+		maxDevices = UnlimitedDevices
+	}
+
+	return LicenseKey{ServerSerial: serial, MaxDevices: maxDevices, Signature: signature}, nil
+}
+
+// String encodes the key as accepted by LICENSE SERVER
+func (k LicenseKey) String() string {
+	count := k.MaxDevices
+	if count == UnlimitedDevices {
+		count = 0
+	}
+	return k.ServerSerial + "," + strconv.Itoa(count) + "," + k.Signature
+}
+
+// Unlimited reports whether the key allows any number of devices
+func (k LicenseKey) Unlimited() bool {
+	return k.MaxDevices == UnlimitedDevices
+}
@@ -0,0 +1,95 @@
+package virtualhere
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// redacted replaces secret command arguments in logs, errors and transcripts
+const redacted = "<redacted>"
+
+// LogLevels sets the level at which each command outcome is logged
+type LogLevels struct {
+	Success slog.Level // The daemon accepted the command or returned data
+	Failure slog.Level // The daemon answered FAILED or ERROR
+	Error   slog.Level // The command could not be exchanged with the daemon
+}
+
+// DefaultLogLevels logs routine traffic at debug level and problems above it
+var DefaultLogLevels = LogLevels{
+	Success: slog.LevelDebug,
+	Failure: slog.LevelInfo,
+	Error:   slog.LevelWarn,
+}
+
+// WithLogger logs every command sent by the client with its duration, outcome
+// and response size. Passwords and license keys are always redacted.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithLogLevels overrides DefaultLogLevels for the logger set by WithLogger
+func WithLogLevels(levels LogLevels) ClientOption {
+	return func(c *Client) {
+		c.logLevels = &levels
+	}
+}
+
+// logCommand records the outcome of command if a logger is configured
+func (c *Client) logCommand(ctx context.Context, cmd Command, start time.Time, result *CommandResult, err error) {
+	if c.logger == nil {
+		return
+	}
+
+	levels := DefaultLogLevels
+	if c.logLevels != nil {
+		levels = *c.logLevels
+	}
+
+	attrs := []slog.Attr{
+		slog.String("command", cmd.Redacted()),
+		slog.Duration("duration", time.Since(start)),
+	}
+
+	outcome := commandOutcome(result, err)
+	level := levels.Success
+	switch outcome {
+	case OutcomeError:
+		level = levels.Error
+		attrs = append(attrs, slog.String("error", redactOutput(cmd, err.Error())))
+		var retryErr *RetryError
+		if errors.As(err, &retryErr) {
+			attrs = append(attrs, slog.Int("attempts", retryErr.Attempts))
+		}
+	case OutcomeFailure:
+		level = levels.Failure
+		attrs = append(attrs, slog.String("error", result.Error.Error()))
+	}
+
+	attrs = append(attrs, slog.String("outcome", string(outcome)))
+	if result != nil {
+		attrs = append(attrs, slog.Int("response_bytes", len(result.Output)))
+	}
+
+	c.logger.LogAttrs(ctx, level, "virtualhere command", attrs...)
+}
+
+// redactCommand returns an encoded command line with its secret arguments
+// (see ArgSpec.Secret) replaced
+func redactCommand(line string) string {
+	return parseCommand(line).Redacted()
+}
+
+// redactOutput removes the secret arguments of cmd from text, such as a
+// daemon response that echoes them back
+func redactOutput(cmd Command, text string) string {
+	for _, secret := range cmd.secrets() {
+		text = strings.ReplaceAll(text, secret, redacted)
+	}
+	return text
+}
@@ -0,0 +1,172 @@
+package virtualhere
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const (
+	secretPassword   = "pa,ss,word"
+	secretSignature  = "c2lnbmF0dXJl"
+	secretLicenseKey = "S1,10," + secretSignature
+)
+
+// assertNoSecrets fails if text contains the password or the license key
+func assertNoSecrets(t *testing.T, what, text string) {
+	t.Helper()
+	for _, secret := range []string{secretPassword, secretLicenseKey, secretSignature} {
+		if strings.Contains(text, secret) {
+			t.Errorf("%s contains secret %q: %s", what, secret, text)
+		}
+	}
+}
+
+// echoTransport answers every command with response, in which %s is replaced
+// by the command line, as a daemon echoing its arguments back would
+func echoTransport(response string) Transport {
+	return TransportFunc(func(ctx context.Context, command string) (string, error) {
+		return strings.ReplaceAll(response, "%s", command), nil
+	})
+}
+
+// loggingClient returns a client on transport logging every command to buf
+func loggingClient(t *testing.T, transport Transport, buf *bytes.Buffer, opts ...ClientOption) *Client {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client, err := NewClientWithTransport(transport, append([]ClientOption{WithLogger(logger)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// sendSecrets uses a device with the password and licenses a server with the
+// license key, returning the errors
+func sendSecrets(client *Client) []error {
+	return []error{
+		client.Use("raspberrypi.114", secretPassword),
+		client.LicenseServer(secretLicenseKey),
+	}
+}
+
+func TestSecretsInResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{"accepted", "OK"},
+		{"failed", "FAILED"},
+		{"echoed in error", "ERROR: invalid arguments in %s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			client := loggingClient(t, echoTransport(tt.response), &logs)
+
+			for _, err := range sendSecrets(client) {
+				if tt.response == "OK" {
+					if err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				var cmdErr *CommandError
+				if !errors.As(err, &cmdErr) {
+					t.Fatalf("got %v, want a *CommandError", err)
+				}
+				assertNoSecrets(t, "CommandError", err.Error())
+				assertNoSecrets(t, "CommandError.Command", cmdErr.Command)
+				assertNoSecrets(t, "CommandError.Output", cmdErr.Output)
+			}
+
+			if !strings.Contains(logs.String(), "command=USE,raspberrypi.114,<redacted>") {
+				t.Errorf("log does not name the redacted command:\n%s", logs.String())
+			}
+			assertNoSecrets(t, "log", logs.String())
+		})
+	}
+}
+
+func TestSecretsInTransportErrors(t *testing.T) {
+	// A transport whose errors quote the command line
+	quoting := TransportFunc(func(ctx context.Context, command string) (string, error) {
+		return "", fmt.Errorf("dial for %q: %w", command, syscall.ECONNREFUSED)
+	})
+
+	tests := []struct {
+		name string
+		opts []ClientOption
+	}{
+		{"without retries", nil},
+		{"with retries", []ClientOption{WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			client := loggingClient(t, quoting, &logs, tt.opts...)
+
+			for _, err := range sendSecrets(client) {
+				if !errors.Is(err, syscall.ECONNREFUSED) {
+					t.Errorf("got %v, want an error matching ECONNREFUSED", err)
+				}
+				assertNoSecrets(t, "error", err.Error())
+
+				var retryErr *RetryError
+				if errors.As(err, &retryErr) {
+					assertNoSecrets(t, "RetryError", retryErr.Error())
+				} else if tt.opts != nil {
+					t.Errorf("got %v, want a *RetryError", err)
+				}
+			}
+			assertNoSecrets(t, "log", logs.String())
+		})
+	}
+}
+
+func TestSecretsInTranscripts(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport Transport
+	}{
+		{"echoed in response", echoTransport("ERROR: invalid arguments in %s")},
+		{"echoed in error", TransportFunc(func(ctx context.Context, command string) (string, error) {
+			return "", fmt.Errorf("write %s: broken pipe", command)
+		})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var transcript bytes.Buffer
+			client, err := NewClientWithTransport(NewRecordingTransport(tt.transport, &transcript))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sendSecrets(client)
+
+			entries, err := ReadTranscript(&transcript)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"USE,raspberrypi.114,<redacted>", "LICENSE SERVER,<redacted>"}
+			if len(entries) != len(want) {
+				t.Fatalf("recorded %d entries, want %d", len(entries), len(want))
+			}
+			for i, entry := range entries {
+				if entry.Command != want[i] {
+					t.Errorf("entry %d command = %q, want %q", i+1, entry.Command, want[i])
+				}
+				assertNoSecrets(t, "transcript response", entry.Response)
+				assertNoSecrets(t, "transcript error", entry.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package virtualhere
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcome classifies how a command ended
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success" // The daemon accepted the command or returned data
+	OutcomeFailure Outcome = "failure" // The daemon answered FAILED or ERROR
+	OutcomeError   Outcome = "error"   // The command could not be exchanged with the daemon
+)
+
+// commandOutcome returns the Outcome of an executed command
+func commandOutcome(result *CommandResult, err error) Outcome {
+	switch {
+	case err != nil:
+		return OutcomeError
+	case !result.Success:
+		return OutcomeFailure
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// Metrics receives measurements of every command a Client executes. Commands
+// are identified by their verb (e.g. "USE"), never by their arguments.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	CommandStarted(verb string)
+	CommandFinished(verb string, outcome Outcome, duration time.Duration)
+}
+
+// WithMetrics reports every command executed by the client to m
+func WithMetrics(m Metrics) ClientOption {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency
+// histogram buckets used by NewCommandMetrics when none are given
+var DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// CommandMetrics is the built-in Metrics implementation. It keeps per-command
+// latency histograms, outcome counters and in-flight gauges, and serves them
+// in the Prometheus text exposition format.
+type CommandMetrics struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	commands map[string]*commandStats
+}
+
+// commandStats holds the measurements of a single verb
+type commandStats struct {
+	inFlight     int64
+	outcomes     map[Outcome]uint64
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+// NewCommandMetrics returns an empty CommandMetrics using the given latency
+// bucket upper bounds in seconds, or DefaultLatencyBuckets if none are given
+func NewCommandMetrics(buckets ...float64) *CommandMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &CommandMetrics{
+		buckets:  buckets,
+		commands: make(map[string]*commandStats),
+	}
+}
+
+// stats returns the measurements of verb, creating them if needed.
+// m.mu must be held.
+func (m *CommandMetrics) stats(verb string) *commandStats {
+	stats, ok := m.commands[verb]
+	if !ok {
+		stats = &commandStats{
+			outcomes:     make(map[Outcome]uint64),
+			bucketCounts: make([]uint64, len(m.buckets)),
+		}
+		m.commands[verb] = stats
+	}
+	return stats
+}
+
+// CommandStarted increments the in-flight gauge of verb
+func (m *CommandMetrics) CommandStarted(verb string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(verb).inFlight++
+}
+
+// CommandFinished decrements the in-flight gauge of verb and records the
+// outcome and latency of the command
+func (m *CommandMetrics) CommandFinished(verb string, outcome Outcome, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats(verb)
+	stats.inFlight--
+	stats.outcomes[outcome]++
+	stats.count++
+
+	seconds := duration.Seconds()
+	stats.sum += seconds
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			stats.bucketCounts[i]++
+		}
+	}
+}
+
+// WritePrometheus writes all metrics to w in the Prometheus text exposition format
+func (m *CommandMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	verbs := make([]string, 0, len(m.commands))
+	for verb := range m.commands {
+		verbs = append(verbs, verb)
+	}
+	sort.Strings(verbs)
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "# HELP virtualhere_commands_total Commands executed by the VirtualHere client, by outcome.")
+	fmt.Fprintln(bw, "# TYPE virtualhere_commands_total counter")
+	for _, verb := range verbs {
+		for _, outcome := range []Outcome{OutcomeSuccess, OutcomeFailure, OutcomeError} {
+			fmt.Fprintf(bw, "virtualhere_commands_total{command=%s,outcome=%q} %d\n",
+				labelValue(verb), outcome, m.commands[verb].outcomes[outcome])
+		}
+	}
+
+	fmt.Fprintln(bw, "# HELP virtualhere_commands_in_flight Commands currently waiting for or exchanging with the VirtualHere client.")
+	fmt.Fprintln(bw, "# TYPE virtualhere_commands_in_flight gauge")
+	for _, verb := range verbs {
+		fmt.Fprintf(bw, "virtualhere_commands_in_flight{command=%s} %d\n", labelValue(verb), m.commands[verb].inFlight)
+	}
+
+	fmt.Fprintln(bw, "# HELP virtualhere_command_duration_seconds Latency of commands sent to the VirtualHere client.")
+	fmt.Fprintln(bw, "# TYPE virtualhere_command_duration_seconds histogram")
+	for _, verb := range verbs {
+		stats := m.commands[verb]
+		label := labelValue(verb)
+		for i, bound := range m.buckets {
+			fmt.Fprintf(bw, "virtualhere_command_duration_seconds_bucket{command=%s,le=%q} %d\n",
+				label, strconv.FormatFloat(bound, 'g', -1, 64), stats.bucketCounts[i])
+		}
+		fmt.Fprintf(bw, "virtualhere_command_duration_seconds_bucket{command=%s,le=\"+Inf\"} %d\n", label, stats.count)
+		fmt.Fprintf(bw, "virtualhere_command_duration_seconds_sum{command=%s} %s\n", label, strconv.FormatFloat(stats.sum, 'g', -1, 64))
+		fmt.Fprintf(bw, "virtualhere_command_duration_seconds_count{command=%s} %d\n", label, stats.count)
+	}
+
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format
+func (m *CommandMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WritePrometheus(w)
+}
+
+// labelValue quotes a Prometheus label value, escaping backslashes, quotes and newlines
+func labelValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
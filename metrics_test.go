@@ -0,0 +1,141 @@
+package virtualhere
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exposition returns the samples written by WritePrometheus, keyed by series
+func exposition(t *testing.T, m *CommandMetrics) map[string]string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := m.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		if i < 0 {
+			t.Fatalf("malformed sample %q", line)
+		}
+		samples[line[:i]] = line[i+1:]
+	}
+	return samples
+}
+
+// assertSamples fails for every series of want whose value differs in got
+func assertSamples(t *testing.T, got map[string]string, want map[string]string) {
+	t.Helper()
+	for series, value := range want {
+		if got[series] != value {
+			t.Errorf("%s = %q, want %q", series, got[series], value)
+		}
+	}
+}
+
+func TestWritePrometheusHistogram(t *testing.T) {
+	m := NewCommandMetrics(1, 0.01, 0.1)
+	for _, d := range []time.Duration{5 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second} {
+		m.CommandStarted("LIST")
+		m.CommandFinished("LIST", OutcomeSuccess, d)
+	}
+	m.CommandStarted("USE")
+	m.CommandFinished("USE", OutcomeFailure, time.Millisecond)
+
+	samples := exposition(t, m)
+	assertSamples(t, samples, map[string]string{
+		// Buckets are cumulative and sorted, +Inf equals the count
+		`virtualhere_command_duration_seconds_bucket{command="LIST",le="0.01"}`: "1",
+		`virtualhere_command_duration_seconds_bucket{command="LIST",le="0.1"}`:  "3",
+		`virtualhere_command_duration_seconds_bucket{command="LIST",le="1"}`:    "4",
+		`virtualhere_command_duration_seconds_bucket{command="LIST",le="+Inf"}`: "5",
+		`virtualhere_command_duration_seconds_count{command="LIST"}`:            "5",
+		`virtualhere_command_duration_seconds_bucket{command="USE",le="+Inf"}`:  "1",
+		`virtualhere_command_duration_seconds_count{command="USE"}`:             "1",
+		`virtualhere_command_duration_seconds_sum{command="USE"}`:               "0.001",
+
+		`virtualhere_commands_total{command="LIST",outcome="success"}`: "5",
+		`virtualhere_commands_total{command="LIST",outcome="failure"}`: "0",
+		`virtualhere_commands_total{command="USE",outcome="failure"}`:  "1",
+		`virtualhere_commands_total{command="USE",outcome="error"}`:    "0",
+	})
+	if sum := samples[`virtualhere_command_duration_seconds_sum{command="LIST"}`]; !strings.HasPrefix(sum, "2.605") {
+		t.Errorf("LIST duration sum = %s, want 2.605", sum)
+	}
+}
+
+func TestWritePrometheusEscapesLabels(t *testing.T) {
+	m := NewCommandMetrics()
+	m.CommandStarted("A\"B\\C\nD")
+
+	samples := exposition(t, m)
+	if got := samples[`virtualhere_commands_in_flight{command="A\"B\\C\nD"}`]; got != "1" {
+		t.Errorf("in-flight gauge with escaped label = %q, want 1; samples: %q", got, samples)
+	}
+}
+
+func TestInFlightGauge(t *testing.T) {
+	m := NewCommandMetrics()
+	release := make(chan struct{})
+	transport := TransportFunc(func(ctx context.Context, command string) (string, error) {
+		<-release
+		return "OK", nil
+	})
+	client, err := NewClientWithTransport(transport, WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const series = `virtualhere_commands_in_flight{command="CLEAR LOG"}`
+	done := make(chan error)
+	for range 2 {
+		go func() { done <- client.ClearLog() }()
+	}
+
+	// Both commands count while one exchanges and the other waits its turn
+	deadline := time.Now().Add(5 * time.Second)
+	for exposition(t, m)[series] != "2" {
+		if time.Now().After(deadline) {
+			t.Fatalf("in-flight gauge = %q, want 2", exposition(t, m)[series])
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	release <- struct{}{}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	assertSamples(t, exposition(t, m), map[string]string{series: "1"})
+
+	release <- struct{}{}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	assertSamples(t, exposition(t, m), map[string]string{
+		series: "0",
+		`virtualhere_commands_total{command="CLEAR LOG",outcome="success"}`: "2",
+	})
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	m := NewCommandMetrics()
+	m.CommandStarted("LIST")
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "# TYPE virtualhere_command_duration_seconds histogram\n") {
+		t.Errorf("body lacks the histogram type:\n%s", body)
+	}
+}
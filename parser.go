@@ -2,12 +2,29 @@ package virtualhere
 
 import (
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"strings"
 )
 
+// WithStrictParsing makes command output parsers report lines they do not
+// recognise and malformed XML as ErrInvalidResponse, with the offending line.
+// By default parsing is lenient: unknown lines are skipped, so that output of
+// newer clients can still be read.
+func WithStrictParsing(strict bool) ClientOption {
+	return func(c *Client) {
+		c.strict = strict
+	}
+}
+
+// invalidLine reports an unrecognised line of a command's output in strict mode
+func invalidLine(verb string, number int, line, reason string) error {
+	return fmt.Errorf("%w: %s line %d %q: %s", ErrInvalidResponse, verb, number, line, reason)
+}
+
 // parseListOutput parses the output of the LIST command
 // It extracts hubs and devices from the formatted text output
-func parseListOutput(output string) (*ClientState, error) {
+func parseListOutput(output string, strict bool) (*ClientState, error) {
 	state := &ClientState{
 		Hubs: make([]Hub, 0),
 	}
@@ -15,11 +32,11 @@ func parseListOutput(output string) (*ClientState, error) {
 	lines := strings.Split(output, "\n")
 	var currentHub *Hub
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
 
 		// Skip header and empty lines
-		if line == "" || strings.HasPrefix(line, "VirtualHere IPC") ||
+		if line == "" || strings.HasPrefix(line, "VirtualHere IPC") || strings.HasPrefix(line, "VirtualHere Client IPC") ||
 			strings.HasPrefix(line, "(Value in brackets") {
 			continue
 		}
@@ -30,37 +47,58 @@ func parseListOutput(output string) (*ClientState, error) {
 			if hub.Name != "" {
 				state.Hubs = append(state.Hubs, hub)
 				currentHub = &state.Hubs[len(state.Hubs)-1]
+			} else if strict {
+				return nil, invalidLine("LIST", i+1, line, "malformed hub line")
 			}
 			continue
 		}
 
 		// Parse device line (starts with -->)
-		if strings.HasPrefix(line, "-->") && currentHub != nil {
+		if strings.HasPrefix(line, "-->") {
+			if currentHub == nil {
+				if strict {
+					return nil, invalidLine("LIST", i+1, line, "device listed before any hub")
+				}
+				continue
+			}
 			device := parseDeviceLine(line)
 			if device.Name != "" {
 				currentHub.Devices = append(currentHub.Devices, device)
+			} else if strict {
+				return nil, invalidLine("LIST", i+1, line, "malformed device line")
 			}
 			continue
 		}
 
 		// Parse status lines
-		if strings.Contains(line, "Auto-Find currently") {
-			state.AutoFindEnabled = strings.Contains(line, "on")
-		}
-		if strings.Contains(line, "Auto-Use All currently") {
-			state.AutoUseAllEnabled = strings.Contains(line, "on")
-		}
-		if strings.Contains(line, "Reverse Lookup currently") {
-			state.ReverseLookup = strings.Contains(line, "on")
-		}
-		if strings.Contains(line, "running as a service") {
+		switch {
+		case strings.Contains(line, "Auto-Find currently"):
+			state.AutoFindEnabled = parseOnOff(line)
+		case strings.Contains(line, "Auto-Use All currently"):
+			state.AutoUseAllEnabled = parseOnOff(line)
+		case strings.Contains(line, "Reverse SSL Lookup currently"):
+			state.SSLReverseLookup = parseOnOff(line)
+		case strings.Contains(line, "Reverse Lookup currently"):
+			state.ReverseLookup = parseOnOff(line)
+		case strings.Contains(line, "running as a service"):
 			state.RunningAsService = !strings.Contains(line, "not")
+		default:
+			if strict {
+				return nil, invalidLine("LIST", i+1, line, "unrecognised line")
+			}
 		}
 	}
 
 	return state, nil
 }
 
+// parseOnOff reports whether a status line such as "Auto-Find currently on"
+// ends in "on"
+func parseOnOff(line string) bool {
+	line = strings.ToLower(strings.TrimRight(line, ". "))
+	return strings.HasSuffix(line, " on")
+}
+
 // parseHubLine parses a hub line from the LIST output
 // Format: "Hub Name (address:port)"
 func parseHubLine(line string) Hub {
@@ -77,13 +115,23 @@ func parseHubLine(line string) Hub {
 	}
 
 	hub.Address = strings.TrimSpace(line[startIdx+1 : endIdx])
+	if hub.Address == "" {
+		return hub
+	}
 	hub.Name = strings.TrimSpace(line[:startIdx])
 
 	return hub
 }
 
 // parseDeviceLine parses a device line from the LIST output
-// Format: "--> Device Name (address)" or "--> Device Name (address) *" (if auto-use)
+// Format: "--> Device Name (address)", optionally followed by " *" (auto-use)
+// and " (In-use by you)" or " (In-use by <holder>)".
+//
+// Two forms are accepted without having been seen in a real capture: a
+// device with a nickname listed as "Nickname [Device Name]", and a holder
+// given as "(In-use by: holder (host))". Both are assumptions about the
+// client's output; if it differs, the nickname stays part of Name and the
+// holder keeps its host suffix, and GET CLIENT STATE remains authoritative.
 func parseDeviceLine(line string) Device {
 	device := Device{}
 
@@ -91,33 +139,94 @@ func parseDeviceLine(line string) Device {
 	line = strings.TrimPrefix(line, "-->")
 	line = strings.TrimSpace(line)
 
-	// Check for auto-use marker (*)
-	if strings.HasSuffix(line, "*") {
-		device.AutoUse = true
-		line = strings.TrimSuffix(line, "*")
-		line = strings.TrimSpace(line)
-	}
+	// Peel off the markers following the address, last one first
+	for line != "" {
+		if strings.HasSuffix(line, "*") {
+			device.AutoUse = true
+			line = strings.TrimSpace(strings.TrimSuffix(line, "*"))
+			continue
+		}
 
-	// Find the address in parentheses
-	startIdx := strings.LastIndex(line, "(")
-	endIdx := strings.LastIndex(line, ")")
+		group, rest, ok := cutTrailingGroup(line)
+		if !ok {
+			return Device{}
+		}
+		line = rest
+
+		if holder, ok := cutInUsePrefix(group); ok {
+			device.InUse = true
+			if strings.EqualFold(holder, "you") {
+				device.InUseByMe = true
+			} else {
+				device.Holder = holder
+			}
+			continue
+		}
 
-	if startIdx == -1 || endIdx == -1 || startIdx >= endIdx {
-		return device
+		device.Address = group
+		break
 	}
 
-	device.Address = strings.TrimSpace(line[startIdx+1 : endIdx])
-	device.Name = strings.TrimSpace(line[:startIdx])
+	device.Name = line
+	if open := strings.LastIndex(line, " ["); open > 0 && strings.HasSuffix(line, "]") {
+		device.Nickname = strings.TrimSpace(line[:open])
+		device.Name = strings.TrimSpace(line[open+2 : len(line)-1])
+	}
 
+	if device.Address == "" {
+		return Device{}
+	}
 	return device
 }
 
+// cutTrailingGroup splits "text (group)" into the contents of the last,
+// possibly nested, parenthesized group and the text before it
+func cutTrailingGroup(line string) (group, rest string, ok bool) {
+	if !strings.HasSuffix(line, ")") {
+		return "", "", false
+	}
+
+	depth := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(line[i+1 : len(line)-1]), strings.TrimSpace(line[:i]), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// cutInUsePrefix returns the holder named in an in-use marker such as
+// "In-use by you", "In use by bob" or the assumed "In-use by: bob (bob-pc)"
+func cutInUsePrefix(group string) (string, bool) {
+	lower := strings.ToLower(group)
+	for _, prefix := range []string{"in-use by", "in use by"} {
+		if strings.HasPrefix(lower, prefix) {
+			holder := strings.TrimPrefix(group[len(prefix):], ":")
+			return strings.TrimSpace(holder), true
+		}
+	}
+	return "", false
+}
+
 // parseClientStateXML parses the XML output from GET CLIENT STATE command
-func parseClientStateXML(output string) (*XMLClientState, error) {
+func parseClientStateXML(output string, strict bool) (*XMLClientState, error) {
 	var state XMLClientState
 	err := xml.Unmarshal([]byte(output), &state)
 	if err != nil {
-		return nil, err
+		if !strict {
+			return nil, err
+		}
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%w: GET CLIENT STATE line %d: %w", ErrInvalidResponse, syntaxErr.Line, err)
+		}
+		return nil, fmt.Errorf("%w: GET CLIENT STATE: %w", ErrInvalidResponse, err)
 	}
 	return &state, nil
 }
@@ -131,18 +240,21 @@ func parseClientStateXML(output string) (*XMLClientState, error) {
 // PRODUCT ID: 0x4ee7
 // SERIAL: 652e1e0d
 // IN USE BY: NO ONE
-func parseDeviceInfo(output string) (*DeviceInfo, error) {
+func parseDeviceInfo(output string, strict bool) (*DeviceInfo, error) {
 	info := &DeviceInfo{}
 	lines := strings.Split(output, "\n")
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
 		if line == "" {
 			continue
 		}
 
 		parts := strings.SplitN(line, ":", 2)
 		if len(parts) != 2 {
+			if strict {
+				return nil, invalidLine("DEVICE INFO", i+1, line, "expected KEY: value")
+			}
 			continue
 		}
 
@@ -164,6 +276,10 @@ func parseDeviceInfo(output string) (*DeviceInfo, error) {
 			info.Serial = value
 		case "IN USE BY":
 			info.InUseBy = value
+		default:
+			if strict {
+				return nil, invalidLine("DEVICE INFO", i+1, line, "unknown field")
+			}
 		}
 	}
 
@@ -183,18 +299,21 @@ func parseDeviceInfo(output string) (*DeviceInfo, error) {
 // INTERFACE:
 // SERIAL NUMBER: 07370b72-f03f-4f6e-b930-33fd5d8930f5
 // EASYFIND: not enabled
-func parseServerInfo(output string) (*ServerInfo, error) {
+func parseServerInfo(output string, strict bool) (*ServerInfo, error) {
 	info := &ServerInfo{}
 	lines := strings.Split(output, "\n")
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
 		if line == "" {
 			continue
 		}
 
 		parts := strings.SplitN(line, ":", 2)
 		if len(parts) != 2 {
+			if strict {
+				return nil, invalidLine("SERVER INFO", i+1, line, "expected KEY: value")
+			}
 			continue
 		}
 
@@ -224,12 +343,64 @@ func parseServerInfo(output string) (*ServerInfo, error) {
 			info.SerialNumber = value
 		case "EASYFIND":
 			info.EasyFind = value
+		default:
+			if strict {
+				return nil, invalidLine("SERVER INFO", i+1, line, "unknown field")
+			}
 		}
 	}
 
 	return info, nil
 }
 
+// parseLicenses parses the output of LIST LICENSES, one license per line
+// Format: "<server serial>,<max devices>,<signature>", optionally followed by
+// a status in parentheses, e.g. "(valid)"
+func parseLicenses(output string, strict bool) ([]License, error) {
+	licenses := make([]License, 0)
+	for i, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		license := License{Key: line}
+		if status, key, ok := cutTrailingGroup(line); ok {
+			license.Key, license.Status = key, status
+		}
+		key, err := ParseLicenseKey(license.Key)
+		if err == nil {
+			license.ServerSerial = key.ServerSerial
+			license.MaxDevices = key.MaxDevices
+		} else if strict {
+			// The line holds a license key, so it is left out of the error
+			return nil, fmt.Errorf("%w: LIST LICENSES line %d: %w", ErrInvalidResponse, i+1, err)
+		}
+		licenses = append(licenses, license)
+	}
+	return licenses, nil
+}
+
+// parseReverseClients parses the output of LIST REVERSE for serverSerial
+// Format: one client address per line, optionally as "<server serial>,<address>"
+func parseReverseClients(serverSerial, output string) ([]ReverseClient, error) {
+	clients := make([]ReverseClient, 0)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		client := ReverseClient{ServerSerial: serverSerial, ClientAddress: line}
+		if serial, address, ok := strings.Cut(line, ","); ok {
+			client.ServerSerial = strings.TrimSpace(serial)
+			client.ClientAddress = strings.TrimSpace(address)
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
 // isSuccessResponse checks if the response indicates success
 func isSuccessResponse(output string) bool {
 	return strings.HasPrefix(output, "OK")
@@ -0,0 +1,208 @@
+package virtualhere
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDeviceLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want Device
+	}{
+		{"--> Ultra USB 3.0 (raspberrypi.114)", Device{Name: "Ultra USB 3.0", Address: "raspberrypi.114"}},
+		{"--> CP2102 USB to UART Bridge Controller (linuxhub.21) *", Device{Name: "CP2102 USB to UART Bridge Controller", Address: "linuxhub.21", AutoUse: true}},
+		{"--> Mi 10 (TryanksPC.14) (In-use by you)", Device{Name: "Mi 10", Address: "TryanksPC.14", InUse: true, InUseByMe: true}},
+		{"--> FT232R (raspberrypi.4) * (In-use by you)", Device{Name: "FT232R", Address: "raspberrypi.4", AutoUse: true, InUse: true, InUseByMe: true}},
+		{"--> Disk (raspberrypi.3) (In-use by: alice (alice-laptop))", Device{Name: "Disk", Address: "raspberrypi.3", InUse: true, Holder: "alice (alice-laptop)"}},
+		{"--> Disk (raspberrypi.3) (In use by bob)", Device{Name: "Disk", Address: "raspberrypi.3", InUse: true, Holder: "bob"}},
+		{"--> jtag [Product (x)] (raspberrypi.5)", Device{Name: "Product (x)", Nickname: "jtag", Address: "raspberrypi.5"}},
+		{"--> bench-scope [DS1054Z] (TryanksPC.15) * (In-use by lab-pc)", Device{Name: "DS1054Z", Nickname: "bench-scope", Address: "TryanksPC.15", AutoUse: true, InUse: true, Holder: "lab-pc"}},
+		{"--> USB (Mass) Storage (raspberrypi.6)", Device{Name: "USB (Mass) Storage", Address: "raspberrypi.6"}},
+		{"--> No address", Device{}},
+		{"--> (In-use by you)", Device{}},
+		{"--> Unbalanced (raspberrypi.7", Device{}},
+	}
+
+	for _, tt := range tests {
+		if got := parseDeviceLine(tt.line); got != tt.want {
+			t.Errorf("parseDeviceLine(%q)\n got %+v\nwant %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseListOutput(t *testing.T) {
+	const output = `VirtualHere Client IPC, below are the available devices:
+(Value in brackets = address, * = Auto-Use)
+
+Windows Hub (192.168.31.145:7575)
+   --> Mi 10 (TryanksPC.14) (In-use by you)
+   --> FT232R (TryanksPC.16) * (In-use by you)
+   --> Disk (TryanksPC.17) (In-use by: alice (alice-laptop))
+   --> jtag [Product (x)] (TryanksPC.18)
+
+Linux Hub (10.0.0.7:7575)
+   --> CP2102 USB to UART Bridge Controller (linuxhub.21) *
+
+Auto-Find currently on
+Auto-Use All currently off
+Reverse Lookup currently on
+Reverse SSL Lookup currently off
+VirtualHere Client is running as a service
+`
+
+	want := &ClientState{
+		Hubs: []Hub{
+			{Name: "Windows Hub", Address: "192.168.31.145:7575", Devices: []Device{
+				{Name: "Mi 10", Address: "TryanksPC.14", InUse: true, InUseByMe: true},
+				{Name: "FT232R", Address: "TryanksPC.16", AutoUse: true, InUse: true, InUseByMe: true},
+				{Name: "Disk", Address: "TryanksPC.17", InUse: true, Holder: "alice (alice-laptop)"},
+				{Name: "Product (x)", Nickname: "jtag", Address: "TryanksPC.18"},
+			}},
+			{Name: "Linux Hub", Address: "10.0.0.7:7575", Devices: []Device{
+				{Name: "CP2102 USB to UART Bridge Controller", Address: "linuxhub.21", AutoUse: true},
+			}},
+		},
+		AutoFindEnabled:  true,
+		ReverseLookup:    true,
+		RunningAsService: true,
+	}
+
+	for _, strict := range []bool{false, true} {
+		got, err := parseListOutput(output, strict)
+		if err != nil {
+			t.Fatalf("parseListOutput(strict=%v): %v", strict, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("parseListOutput(strict=%v)\n got %+v\nwant %+v", strict, got, want)
+		}
+	}
+}
+
+// seedCorpus adds the outputs of the corpus samples of kind to the fuzz corpus
+func seedCorpus(f *testing.F, kind string) {
+	for _, sample := range loadCorpus(f, kind) {
+		f.Add(sample.output)
+	}
+}
+
+// checkStrict verifies that strict parsing either fails with
+// ErrInvalidResponse or agrees with lenient parsing
+func checkStrict(t *testing.T, lenient, strict any, err error) {
+	t.Helper()
+	if err != nil {
+		if !errors.Is(err, ErrInvalidResponse) {
+			t.Fatalf("strict error %v does not match ErrInvalidResponse", err)
+		}
+		return
+	}
+	if !reflect.DeepEqual(lenient, strict) {
+		t.Fatalf("strict result %+v differs from lenient result %+v", strict, lenient)
+	}
+}
+
+func FuzzParseListOutput(f *testing.F) {
+	seedCorpus(f, "list")
+	f.Add("Hub (h:7575)\n--> (In-use by you) (h.1) * (In-use by: a (b))")
+	f.Add("--> [] ((()))")
+
+	f.Fuzz(func(t *testing.T, output string) {
+		lenient, err := parseListOutput(output, false)
+		if err != nil {
+			t.Fatalf("lenient parsing failed: %v", err)
+		}
+		for _, hub := range lenient.Hubs {
+			if hub.Name == "" || hub.Address == "" {
+				t.Fatalf("hub without name or address: %+v", hub)
+			}
+			for _, device := range hub.Devices {
+				if device.Name == "" || device.Address == "" {
+					t.Fatalf("device without name or address: %+v", device)
+				}
+				if device.InUseByMe && !device.InUse {
+					t.Fatalf("device in use by me but not in use: %+v", device)
+				}
+			}
+		}
+
+		strict, err := parseListOutput(output, true)
+		checkStrict(t, lenient, strict, err)
+	})
+}
+
+func FuzzParseDeviceInfo(f *testing.F) {
+	seedCorpus(f, "device_info")
+	f.Add("ADDRESS:\nVENDOR ID: : :")
+
+	f.Fuzz(func(t *testing.T, output string) {
+		lenient, err := parseDeviceInfo(output, false)
+		if err != nil {
+			t.Fatalf("lenient parsing failed: %v", err)
+		}
+		strict, err := parseDeviceInfo(output, true)
+		checkStrict(t, lenient, strict, err)
+	})
+}
+
+func FuzzParseServerInfo(f *testing.F) {
+	seedCorpus(f, "server_info")
+	f.Add("NAME:\nMAX DEVICES: -1\nCONNECTED FOR: x sec")
+
+	f.Fuzz(func(t *testing.T, output string) {
+		lenient, err := parseServerInfo(output, false)
+		if err != nil {
+			t.Fatalf("lenient parsing failed: %v", err)
+		}
+		strict, err := parseServerInfo(output, true)
+		checkStrict(t, lenient, strict, err)
+
+		// Converting the fields must fail cleanly, never panic
+		if _, err := lenient.Details(); err != nil && !errors.Is(err, ErrInvalidResponse) {
+			t.Fatalf("Details error %v does not match ErrInvalidResponse", err)
+		}
+	})
+}
+
+func FuzzParseClientStateXML(f *testing.F) {
+	seedCorpus(f, "client_state")
+	f.Add(`<state><server><connection state="x"/><device state="3" autoUse=""/></server></state>`)
+	f.Add(`<state><server><device address="-1"/></server>`)
+
+	f.Fuzz(func(t *testing.T, output string) {
+		lenient, lenientErr := parseClientStateXML(output, false)
+		strict, err := parseClientStateXML(output, true)
+		if (lenientErr == nil) != (err == nil) {
+			t.Fatalf("lenient error %v, strict error %v", lenientErr, err)
+		}
+		if lenientErr != nil {
+			if !errors.Is(err, ErrInvalidResponse) {
+				t.Fatalf("strict error %v does not match ErrInvalidResponse", err)
+			}
+			return
+		}
+		if !reflect.DeepEqual(lenient, strict) {
+			t.Fatalf("strict result %+v differs from lenient result %+v", strict, lenient)
+		}
+		lenient.Devices()
+	})
+}
+
+func FuzzParseLicenses(f *testing.F) {
+	seedCorpus(f, "list_licenses")
+	f.Add("S1,0,sig (valid)\n,,\nS2,-1,x\nS3,1,(")
+
+	f.Fuzz(func(t *testing.T, output string) {
+		lenient, err := parseLicenses(output, false)
+		if err != nil {
+			t.Fatalf("lenient parsing failed: %v", err)
+		}
+		for _, license := range lenient {
+			if license.ServerSerial != "" && license.MaxDevices == 0 {
+				t.Fatalf("decoded license with a device count of 0: %+v", license)
+			}
+		}
+		strict, err := parseLicenses(output, true)
+		checkStrict(t, lenient, strict, err)
+	})
+}
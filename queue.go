@@ -0,0 +1,144 @@
+package virtualhere
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// Priority orders commands waiting for the IPC channel. Commands with a higher
+// priority are sent first; commands of equal priority are sent in arrival order.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1 // e.g. background polling
+	PriorityNormal Priority = 0  // default for most commands
+	PriorityHigh   Priority = 1  // default for STOP USING commands
+)
+
+// priorityKey is the context key under which WithPriority stores a Priority
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx that queues every command sent with it at p,
+// overriding the command's default priority
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// withDefaultPriority returns ctx queueing its commands at p, unless ctx
+// already carries a priority set by WithPriority
+func withDefaultPriority(ctx context.Context, p Priority) context.Context {
+	if _, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return ctx
+	}
+	return WithPriority(ctx, p)
+}
+
+// contextPriority returns the priority set on ctx by WithPriority, or the
+// command's default priority from its CommandSpec
+func contextPriority(ctx context.Context, fallback Priority) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return fallback
+}
+
+// commandQueue serializes command exchanges. The Unix protocol pairs a request
+// socket with a separate response socket, so only one command may be in flight
+// at a time or callers could read each other's responses. The zero value is an
+// idle queue ready for use.
+type commandQueue struct {
+	mu      sync.Mutex
+	busy    bool
+	seq     uint64
+	waiters waiterHeap
+}
+
+// waiter is a command blocked in acquire until the channel is handed to it
+type waiter struct {
+	priority Priority
+	seq      uint64
+	ready    chan struct{}
+	index    int
+}
+
+// acquire blocks until the caller owns the channel or ctx is done.
+// Every successful acquire must be paired with a release.
+func (q *commandQueue) acquire(ctx context.Context, priority Priority) error {
+	q.mu.Lock()
+	if !q.busy {
+		q.busy = true
+		q.mu.Unlock()
+		return nil
+	}
+
+	w := &waiter{priority: priority, seq: q.seq, ready: make(chan struct{})}
+	q.seq++
+	heap.Push(&q.waiters, w)
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	select {
+	case <-w.ready:
+		// Ownership was handed over while ctx was being cancelled; pass it on
+		q.mu.Unlock()
+		q.release()
+	default:
+		heap.Remove(&q.waiters, w.index)
+		q.mu.Unlock()
+	}
+	return ctx.Err()
+}
+
+// release hands the channel to the highest priority waiter, or marks it idle
+func (q *commandQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.waiters.Len() == 0 {
+		q.busy = false
+		return
+	}
+
+	w := heap.Pop(&q.waiters).(*waiter)
+	close(w.ready)
+}
+
+// waiterHeap implements heap.Interface ordered by priority, then arrival
+type waiterHeap []*waiter
+
+func (h waiterHeap) Len() int { return len(h) }
+
+func (h waiterHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h waiterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waiterHeap) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *waiterHeap) Pop() any {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return w
+}
@@ -0,0 +1,117 @@
+package virtualhere
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForWaiters blocks until n commands wait in q
+func waitForWaiters(t *testing.T, q *commandQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		q.mu.Lock()
+		queued := q.waiters.Len()
+		q.mu.Unlock()
+		if queued >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d waiter(s) queued, want %d", queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// assertIdle fails unless q is free and nobody waits for it
+func assertIdle(t *testing.T, q *commandQueue) {
+	t.Helper()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.busy || q.waiters.Len() != 0 {
+		t.Fatalf("queue busy=%v with %d waiter(s), want idle", q.busy, q.waiters.Len())
+	}
+}
+
+func TestQueuePriorityOrder(t *testing.T) {
+	var q commandQueue
+	if err := q.acquire(context.Background(), PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	priorities := []Priority{PriorityLow, PriorityNormal, PriorityLow, PriorityHigh, PriorityNormal}
+	served := make(chan int, len(priorities))
+	for i, p := range priorities {
+		go func() {
+			if err := q.acquire(context.Background(), p); err != nil {
+				t.Error(err)
+				return
+			}
+			served <- i
+			q.release()
+		}()
+		waitForWaiters(t, &q, i+1)
+	}
+	q.release()
+
+	want := []int{3, 1, 4, 0, 2}
+	for _, i := range want {
+		if got := <-served; got != i {
+			t.Fatalf("served waiter %d, want %d", got, i)
+		}
+	}
+	assertIdle(t, &q)
+}
+
+func TestQueueCancelledWaiterIsSkipped(t *testing.T) {
+	var q commandQueue
+	if err := q.acquire(context.Background(), PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() { cancelled <- q.acquire(ctx, PriorityHigh) }()
+	waitForWaiters(t, &q, 1)
+
+	acquired := make(chan error)
+	go func() { acquired <- q.acquire(context.Background(), PriorityLow) }()
+	waitForWaiters(t, &q, 2)
+
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled acquire returned %v, want context.Canceled", err)
+	}
+
+	q.release()
+	if err := <-acquired; err != nil {
+		t.Fatalf("remaining waiter: %v", err)
+	}
+	q.release()
+	assertIdle(t, &q)
+}
+
+func TestQueueCancelDuringHandover(t *testing.T) {
+	var q commandQueue
+	for range 500 {
+		if err := q.acquire(context.Background(), PriorityNormal); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		result := make(chan error)
+		go func() { result <- q.acquire(ctx, PriorityNormal) }()
+		waitForWaiters(t, &q, 1)
+
+		// Cancel while the channel is being handed to the waiter: whichever
+		// wins, the channel must end up released exactly once
+		go cancel()
+		q.release()
+		if err := <-result; err == nil {
+			q.release()
+		}
+		assertIdle(t, &q)
+	}
+}
@@ -0,0 +1,114 @@
+package virtualhere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how commands are retried after transient IPC failures,
+// such as the sockets not existing yet right after the service was started.
+// Only connection failures are retried, and toggle commands never are.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts including the first; values below 2 disable retries
+	InitialBackoff time.Duration // Delay before the second attempt
+	MaxBackoff     time.Duration // Upper bound for the delay between attempts (0 = no bound)
+	Multiplier     float64       // Growth factor of the delay per attempt (values below 1 mean 2)
+	Jitter         float64       // Random fraction (0-1) by which each delay is shortened or lengthened
+}
+
+// DefaultRetryPolicy rides out a freshly started service creating its sockets
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// WithRetryPolicy enables retrying commands that fail with transient IPC errors
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// RetryError is returned when retries are enabled and a command still failed.
+// It records how many attempts were made.
+type RetryError struct {
+	Verb     string // Command verb, e.g. "LIST" (arguments are omitted)
+	Attempts int    // Number of attempts made
+	Err      error  // Error of the last attempt
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s failed after %d attempt(s): %v", e.Verb, e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// enabled reports whether the policy allows more than one attempt
+func (p RetryPolicy) enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// backoff returns the delay to wait after the given failed attempt (1-based)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// shouldRetry reports whether cmd may be attempted again after err.
+// Toggle commands are not idempotent, so they are never retried.
+func (p RetryPolicy) shouldRetry(cmd Command, err error, attempts int) bool {
+	return attempts < p.MaxAttempts && !cmd.spec().Toggle && isRetryable(err)
+}
+
+// isRetryable classifies errors caused by the daemon not (yet) listening:
+// failed dials, refused connections and missing sockets or pipes
+func isRetryable(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT) || errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,170 @@
+package virtualhere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// dialError is the error a dial to a missing daemon socket fails with
+func dialError(err error) error {
+	return &net.OpError{Op: "dial", Net: "unix", Err: &os.SyscallError{Syscall: "connect", Err: err}}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		if got := policy.backoff(i + 1); got != w*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w*time.Millisecond)
+		}
+	}
+
+	policy.Multiplier = 0
+	if got := policy.backoff(2); got != 200*time.Millisecond {
+		t.Errorf("backoff(2) with multiplier 0 = %v, want the default multiplier of 2", got)
+	}
+
+	policy.MaxBackoff = 0
+	if got := policy.backoff(10); got != 100*time.Millisecond<<9 {
+		t.Errorf("backoff(10) without bound = %v, want %v", got, 100*time.Millisecond<<9)
+	}
+
+	policy = RetryPolicy{InitialBackoff: 100 * time.Millisecond, Jitter: 0.2}
+	for range 100 {
+		if got := policy.backoff(1); got < 80*time.Millisecond || got > 120*time.Millisecond {
+			t.Fatalf("backoff(1) with 20%% jitter = %v, want within 80ms-120ms", got)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"missing socket", dialError(syscall.ENOENT), true},
+		{"refused connection", dialError(syscall.ECONNREFUSED), true},
+		{"wrapped ENOENT", fmt.Errorf("failed to connect to response socket: %w", syscall.ENOENT), true},
+		{"missing pipe", &fs.PathError{Op: "open", Path: `\\.\pipe\vhclient`, Err: fs.ErrNotExist}, true},
+		{"dial timeout", &net.OpError{Op: "dial", Net: "unix", Err: os.ErrDeadlineExceeded}, true},
+		{"read failure", &net.OpError{Op: "read", Net: "unix", Err: io.ErrUnexpectedEOF}, false},
+		{"write failure", &net.OpError{Op: "write", Net: "unix", Err: syscall.EPIPE}, false},
+		{"reset connection", &net.OpError{Op: "read", Net: "unix", Err: syscall.ECONNRESET}, false},
+		{"cancelled", context.Canceled, false},
+		{"other", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+	list := Command{Verb: "LIST"}
+	toggle := Command{Verb: "AUTO USE HUB", Args: []string{"Hub"}}
+	refused := dialError(syscall.ECONNREFUSED)
+
+	if !policy.shouldRetry(list, refused, 1) || !policy.shouldRetry(list, refused, 2) {
+		t.Error("LIST not retried after a refused connection")
+	}
+	if policy.shouldRetry(list, refused, 3) {
+		t.Error("LIST retried beyond MaxAttempts")
+	}
+	if policy.shouldRetry(list, errors.New("boom"), 1) {
+		t.Error("LIST retried after a non-transient error")
+	}
+
+	for _, spec := range commandSpecs {
+		if spec.Toggle && policy.shouldRetry(Command{Verb: spec.Verb}, refused, 1) {
+			t.Errorf("toggle %s retried", spec.Verb)
+		}
+	}
+	if policy.shouldRetry(toggle, refused, 1) {
+		t.Error("AUTO USE HUB retried")
+	}
+	if policy.shouldRetry(Command{Verb: "AUTO USE ALL"}, refused, 1) {
+		t.Error("AUTO USE ALL retried")
+	}
+}
+
+// failingTransport fails the first failures sends with err, then answers OK
+func failingTransport(failures int, err error) (Transport, *int) {
+	calls := 0
+	return TransportFunc(func(ctx context.Context, command string) (string, error) {
+		calls++
+		if calls <= failures {
+			return "", err
+		}
+		return "OK", nil
+	}), &calls
+}
+
+func TestRetryAttempts(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond}
+	refused := dialError(syscall.ECONNREFUSED)
+
+	tests := []struct {
+		name         string
+		verb         string
+		args         []string
+		failures     int
+		err          error
+		wantCalls    int
+		wantAttempts int // 0 = success
+	}{
+		{"recovers", "LIST", nil, 2, refused, 3, 0},
+		{"gives up", "LIST", nil, 10, refused, 4, 4},
+		{"toggle is not retried", "AUTO USE HUB", []string{"Hub"}, 1, refused, 1, 1},
+		{"read failure is not retried", "LIST", nil, 1, &net.OpError{Op: "read", Net: "unix", Err: io.EOF}, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, calls := failingTransport(tt.failures, tt.err)
+			client, err := NewClientWithTransport(transport, WithRetryPolicy(policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd, err := NewCommand(tt.verb, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.Execute(context.Background(), cmd)
+			if *calls != tt.wantCalls {
+				t.Errorf("transport called %d times, want %d", *calls, tt.wantCalls)
+			}
+			if tt.wantAttempts == 0 {
+				if err != nil {
+					t.Fatalf("Execute: %v", err)
+				}
+				return
+			}
+
+			var retryErr *RetryError
+			if !errors.As(err, &retryErr) {
+				t.Fatalf("Execute returned %v, want a *RetryError", err)
+			}
+			if retryErr.Attempts != tt.wantAttempts || retryErr.Verb != tt.verb {
+				t.Errorf("RetryError = %s after %d attempt(s), want %s after %d", retryErr.Verb, retryErr.Attempts, tt.verb, tt.wantAttempts)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error %v does not wrap the transport error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package virtualhere
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// selectorKeys lists the attributes a Selector can match, in documentation order
+var selectorKeys = []string{"vid", "pid", "serial", "vendor", "product", "name", "nickname", "address", "hub", "state"}
+
+// Selector matches remote devices by their attributes. It is parsed from a
+// list of key=value terms separated by commas or spaces, such as
+// "vid=0403,pid=6001,serial=A1*" or "hub=lab2 nickname=jtag-*", and matches a
+// device when every term does.
+//
+// Values are patterns compared without regard to case, in which "*" matches
+// any run of characters and "?" any single character; every other character,
+// including "/", "[" and "\", matches itself. Values containing spaces or
+// commas can be double-quoted, e.g. product="FT232R USB UART". The keys are:
+//
+//   - vid, pid: vendor and product ID as four hex digits, with or without "0x"
+//   - serial, vendor, product, nickname: the device attribute of that name
+//   - name: the nickname if set, otherwise the product name
+//   - address: the device address, e.g. raspberrypi.114
+//   - hub: the name, hostname, address or serial of the device's server
+//   - state: a DeviceState name such as "available"; not a pattern
+//
+// The zero Selector matches every device.
+type Selector struct {
+	terms []selectorTerm
+}
+
+// selectorTerm is one key=value term of a Selector
+type selectorTerm struct {
+	key     string
+	value   string      // Value as given
+	pattern string      // Lower-case wildcard pattern
+	state   DeviceState // Parsed value of a state term
+}
+
+// ParseSelector parses a selector such as "vid=0403,pid=6001". Errors match
+// ErrInvalidSelector.
+func ParseSelector(s string) (Selector, error) {
+	tokens, err := splitSelector(s)
+	if err != nil {
+		return Selector{}, fmt.Errorf("%w: %q: %v", ErrInvalidSelector, s, err)
+	}
+	if len(tokens) == 0 {
+		return Selector{}, fmt.Errorf("%w: empty selector", ErrInvalidSelector)
+	}
+
+	var sel Selector
+	for _, token := range tokens {
+		term, err := parseSelectorTerm(token)
+		if err != nil {
+			return Selector{}, fmt.Errorf("%w: %q: %v", ErrInvalidSelector, s, err)
+		}
+		sel.terms = append(sel.terms, term)
+	}
+	return sel, nil
+}
+
+// splitSelector splits s into terms at commas and spaces outside double quotes
+func splitSelector(s string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	quoted, escaped := false, false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == ',' || r == ' ' || r == '\t' || r == '\n'):
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+			continue
+		}
+		token.WriteRune(r)
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+// parseSelectorTerm parses a single key=value term
+func parseSelectorTerm(token string) (selectorTerm, error) {
+	key, value, ok := strings.Cut(token, "=")
+	if !ok {
+		return selectorTerm{}, fmt.Errorf("term %q is not of the form key=value", token)
+	}
+
+	key = strings.ToLower(strings.TrimSpace(key))
+	if !slices.Contains(selectorKeys, key) {
+		return selectorTerm{}, fmt.Errorf("unknown key %q, want one of %s", key, strings.Join(selectorKeys, ", "))
+	}
+
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return selectorTerm{}, fmt.Errorf("term %q: malformed quoted value", token)
+		}
+		value = unquoted
+	}
+	if value == "" {
+		return selectorTerm{}, fmt.Errorf("term %q has an empty value", token)
+	}
+
+	term := selectorTerm{key: key, value: value, pattern: strings.ToLower(value)}
+	switch key {
+	case "vid", "pid":
+		term.pattern = strings.TrimPrefix(term.pattern, "0x")
+	case "state":
+		if err := term.state.UnmarshalText([]byte(value)); err != nil {
+			return selectorTerm{}, err
+		}
+	}
+	return term, nil
+}
+
+// String returns the selector in the form accepted by ParseSelector
+func (s Selector) String() string {
+	terms := make([]string, len(s.terms))
+	for i, term := range s.terms {
+		value := term.value
+		if strings.ContainsAny(value, " ,\t\n\"\\") {
+			value = strconv.Quote(value)
+		}
+		terms[i] = term.key + "=" + value
+	}
+	return strings.Join(terms, ",")
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Selector) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *Selector) UnmarshalText(text []byte) error {
+	sel, err := ParseSelector(string(text))
+	if err != nil {
+		return err
+	}
+	*s = sel
+	return nil
+}
+
+// Match reports whether device matches every term of the selector
+func (s Selector) Match(device RemoteDevice) bool {
+	for _, term := range s.terms {
+		if !term.match(device) {
+			return false
+		}
+	}
+	return true
+}
+
+// match reports whether the term matches device
+func (t selectorTerm) match(device RemoteDevice) bool {
+	switch t.key {
+	case "vid":
+		return t.glob(fmt.Sprintf("%04x", device.VendorID))
+	case "pid":
+		return t.glob(fmt.Sprintf("%04x", device.ProductID))
+	case "serial":
+		return t.glob(device.Serial)
+	case "vendor":
+		return t.glob(device.Vendor)
+	case "product":
+		return t.glob(device.Product)
+	case "name":
+		return t.glob(device.Name)
+	case "nickname":
+		return t.glob(device.Nickname)
+	case "address":
+		return t.glob(device.Address.String())
+	case "hub":
+		hub := device.Hub
+		return t.glob(hub.Name) || t.glob(hub.Hostname) || t.glob(hub.Serial) ||
+			(!hub.Address.IsZero() && (t.glob(hub.Address.String()) || t.glob(hub.Address.Host)))
+	case "state":
+		return device.State == t.state
+	}
+	return false
+}
+
+// glob matches value against the term's pattern, ignoring case
+func (t selectorTerm) glob(value string) bool {
+	return matchWildcards(t.pattern, strings.ToLower(value))
+}
+
+// matchWildcards reports whether s matches pattern, in which "*" stands for
+// any run of characters and "?" for any single character
+func matchWildcards(pattern, s string) bool {
+	p, v := []rune(pattern), []rune(s)
+	i, j := 0, 0
+	star, resume := -1, 0 // Last "*" seen, and where the text it swallows ends
+
+	for j < len(v) {
+		switch {
+		case i < len(p) && p[i] == '*':
+			star, resume = i, j
+			i++
+		case i < len(p) && (p[i] == '?' || p[i] == v[j]):
+			i++
+			j++
+		case star >= 0:
+			// Let the last "*" swallow one more character and try again
+			resume++
+			i, j = star+1, resume
+		default:
+			return false
+		}
+	}
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+	return i == len(p)
+}
+
+// Find returns the devices of GET CLIENT STATE matching sel. If none match,
+// the error matches ErrNoMatch.
+func (c *Client) Find(sel Selector) ([]RemoteDevice, error) {
+	return c.FindContext(context.Background(), sel)
+}
+
+// FindContext is like Find but uses ctx to cancel or time out the command
+func (c *Client) FindContext(ctx context.Context, sel Selector) ([]RemoteDevice, error) {
+	state, err := c.GetClientStateContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []RemoteDevice
+	for _, device := range state.Devices() {
+		if sel.Match(device) {
+			matches = append(matches, device)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatch, sel)
+	}
+	return matches, nil
+}
+
+// FindOne returns the single device matching sel. The error matches
+// ErrNoMatch if no device matches, or ErrAmbiguousMatch and lists the
+// addresses of the matches if several do.
+func (c *Client) FindOne(sel Selector) (RemoteDevice, error) {
+	return c.FindOneContext(context.Background(), sel)
+}
+
+// FindOneContext is like FindOne but uses ctx to cancel or time out the command
+func (c *Client) FindOneContext(ctx context.Context, sel Selector) (RemoteDevice, error) {
+	matches, err := c.FindContext(ctx, sel)
+	if err != nil {
+		return RemoteDevice{}, err
+	}
+	if len(matches) > 1 {
+		addresses := make([]string, len(matches))
+		for i, device := range matches {
+			addresses[i] = device.Address.String()
+		}
+		return RemoteDevice{}, fmt.Errorf("%w: %s matches %d devices: %s",
+			ErrAmbiguousMatch, sel, len(matches), strings.Join(addresses, ", "))
+	}
+	return matches[0], nil
+}
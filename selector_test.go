@@ -0,0 +1,262 @@
+package virtualhere
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // String of the parsed selector, empty if parsing fails
+	}{
+		{"vid=0403,pid=6001", "vid=0403,pid=6001"},
+		{"VID=0x0403 PID=0X6001", "vid=0x0403,pid=0X6001"},
+		{"  hub=lab2 ,, nickname=jtag-*\t", "hub=lab2,nickname=jtag-*"},
+		{`product="FT232R USB UART"`, `product="FT232R USB UART"`},
+		{`product="a,b" serial=X`, `product="a,b",serial=X`},
+		{`nickname="say \"hi\""`, `nickname="say \"hi\""`},
+		{"serial=[", "serial=["},
+		{`nickname=[lab]\bench`, `nickname="[lab]\\bench"`},
+		{"address=a=b", "address=a=b"},
+		{"state=available", "state=available"},
+		{"state=In-Use-By-Me", "state=In-Use-By-Me"},
+
+		{"", ""},
+		{" , ", ""},
+		{"vid", ""},
+		{"=0403", ""},
+		{"colour=red", ""},
+		{"serial=", ""},
+		{`serial=""`, ""},
+		{`product="open`, ""},
+		{`product="a"b`, ""},
+		{"state=busy", ""},
+		{"state=avail*", ""},
+	}
+
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.input)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidSelector) {
+				t.Errorf("ParseSelector(%q) returned %v, want ErrInvalidSelector", tt.input, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSelector(%q) returned %v", tt.input, err)
+			continue
+		}
+		if got := sel.String(); got != tt.want {
+			t.Errorf("ParseSelector(%q).String() = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseSelectorUnknownKey(t *testing.T) {
+	_, err := ParseSelector("vid=0403,colour=red")
+	if err == nil || !strings.Contains(err.Error(), `unknown key "colour"`) || !strings.Contains(err.Error(), "vid, pid") {
+		t.Errorf("got %v, want an error naming the key and the valid ones", err)
+	}
+}
+
+func TestMatchWildcards(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"**", "abc", true},
+		{"?", "", false},
+		{"?", "é", true},
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"a*c", "abbbc", true},
+		{"a*c", "abbbd", false},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*a", "a", false},
+		{"*a", "bab", false},
+		{"*b", "bab", true},
+		{"jtag/*", "jtag/1", true},
+		{"jtag*", "jtag/bench/1", true},
+		{"a?c", "a/c", true},
+		{"[ab]", "a", false},
+		{"[ab]", "[ab]", true},
+		{`a\*`, `a\bc`, true},
+		{`a\*`, "a*", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchWildcards(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchWildcards(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSelectorMatch(t *testing.T) {
+	hub := HubRef{Name: "Raspberry Hub", Hostname: "raspberrypi", Serial: "b827eb1a2b3c", Address: HubAddress{Host: "raspberrypi", Port: 7575}}
+	device := RemoteDevice{
+		Address:   DeviceAddress{Hub: "raspberrypi", Address: 114},
+		Name:      "jtag/1",
+		Vendor:    "FTDI",
+		Product:   "FT232R USB UART",
+		VendorID:  0x0403,
+		ProductID: 0x6001,
+		Serial:    "A10KZP4N",
+		Nickname:  "jtag/1",
+		State:     DeviceInUseByMe,
+		Hub:       hub,
+	}
+	bench := RemoteDevice{Name: "Scope", Nickname: `[lab]\bench`, Address: DeviceAddress{Hub: "lab", Address: 1}}
+
+	tests := []struct {
+		selector string
+		device   RemoteDevice
+		want     bool
+	}{
+		{"vid=0403", device, true},
+		{"vid=0x0403", device, true},
+		{"vid=04*", device, true},
+		{"vid=6001", device, false},
+		{"vid=0403,pid=6001", device, true},
+		{"vid=0403,pid=1234", device, false},
+		{"serial=a10kzp4n", device, true},
+		{"serial=A1???P4N", device, true},
+		{"serial=A1?P4N", device, false},
+		{"vendor=ftdi", device, true},
+		{`product="ft232r usb uart"`, device, true},
+		{"product=FT232R", device, false},
+		{"product=*USB*", device, true},
+		{"name=jtag/1", device, true},
+		{"nickname=jtag/*", device, true},
+		{"nickname=jtag*", device, true},
+		{"nickname=*", bench, true},
+		{`nickname=[lab]\bench`, bench, true},
+		{"nickname=l", bench, false},
+		{"nickname=[lab]*", bench, true},
+		{"nickname=jtag*", bench, false},
+		{"address=raspberrypi.11?", device, true},
+		{"address=*.114", device, true},
+		{"address=lab.*", device, false},
+		{`hub="Raspberry Hub"`, device, true},
+		{"hub=raspberrypi", device, true},
+		{"hub=raspberrypi:7575", device, true},
+		{"hub=B827*", device, true},
+		{"hub=lab", device, false},
+		{"hub=*:7575", bench, false},
+		{"state=in-use-by-me", device, true},
+		{"state=available", device, false},
+	}
+
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sel.Match(tt.device); got != tt.want {
+			t.Errorf("%s matches %s: %v, want %v", tt.selector, tt.device.Address, got, tt.want)
+		}
+	}
+
+	if !(Selector{}).Match(device) {
+		t.Error("the zero Selector does not match every device")
+	}
+}
+
+func TestSelectorRoundTrip(t *testing.T) {
+	inputs := []string{
+		"vid=0403,pid=6001,serial=A1*",
+		`product="FT232R USB UART" hub=lab2`,
+		`nickname="say \"hi\", then \\ leave"`,
+		`nickname=[lab]\bench`,
+		"state=available",
+	}
+
+	for _, input := range inputs {
+		sel, err := ParseSelector(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := ParseSelector(sel.String())
+		if err != nil {
+			t.Errorf("ParseSelector(%q) of %q: %v", sel.String(), input, err)
+			continue
+		}
+		if !reflect.DeepEqual(again, sel) {
+			t.Errorf("%q did not survive String: %+v, want %+v", input, again, sel)
+		}
+
+		data, err := json.Marshal(map[string]Selector{"sel": sel})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded map[string]Selector
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Errorf("unmarshalling %s: %v", data, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded["sel"], sel) {
+			t.Errorf("%q did not survive MarshalText: %+v, want %+v", input, decoded["sel"], sel)
+		}
+	}
+
+	var sel Selector
+	if err := sel.UnmarshalText([]byte("colour=red")); !errors.Is(err, ErrInvalidSelector) {
+		t.Errorf("UnmarshalText of an invalid selector returned %v", err)
+	}
+}
+
+func TestFindOne(t *testing.T) {
+	state := watchState(
+		`address="114" state="1" product="FT232R USB UART" deviceSerial="A10KZP4N" idVendor="1027" idProduct="24577"`,
+		`address="115" state="1" product="FT232R USB UART" deviceSerial="A20QRS7T" idVendor="1027" idProduct="24577"`,
+		`address="116" state="3" product="ST-LINK/V2" deviceSerial="066DFF" idVendor="1155" idProduct="14152"`,
+	)
+	client, err := NewClientWithTransport(scriptedTransport(map[string]string{"GET CLIENT STATE": state}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		selector string
+		want     string // Address of the device found, or text of the error
+		err      error
+	}{
+		{"serial=A20*", "raspberrypi.115", nil},
+		{"vid=0483", "raspberrypi.116", nil},
+		{"vid=0403,serial=A10KZP4N", "raspberrypi.114", nil},
+		{"vid=1234", "no device matches selector: vid=1234", ErrNoMatch},
+		{"product=FT232R*", "product=FT232R* matches 2 devices: raspberrypi.114, raspberrypi.115", ErrAmbiguousMatch},
+	}
+
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		device, err := client.FindOne(sel)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("FindOne(%s) returned %v, want %v containing %q", tt.selector, err, tt.err, tt.want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FindOne(%s) returned %v", tt.selector, err)
+			continue
+		}
+		if got := device.Address.String(); got != tt.want {
+			t.Errorf("FindOne(%s) = %s, want %s", tt.selector, got, tt.want)
+		}
+	}
+
+	sel, _ := ParseSelector("product=FT232R*")
+	if matches, err := client.Find(sel); err != nil || len(matches) != 2 {
+		t.Errorf("Find(%s) = %d devices, %v", sel, len(matches), err)
+	}
+}
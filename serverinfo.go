@@ -0,0 +1,164 @@
+package virtualhere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnlimitedDevices is the MaxDevices of a server licensed for any number of devices
+const UnlimitedDevices = -1
+
+// ServerDetails is the typed form of the SERVER INFO output
+type ServerDetails struct {
+	Name         string         `json:"name"`
+	Version      Version        `json:"version"`
+	State        string         `json:"state"`         // e.g. "Logged in"
+	Hostname     string         `json:"hostname"`      // Host name part of ADDRESS
+	IP           string         `json:"ip"`            // IP address part of ADDRESS, if reported
+	Port         int            `json:"port"`          // e.g. 7575
+	ConnectedFor time.Duration  `json:"connected_for"` // Time since the connection was established
+	MaxDevices   int            `json:"max_devices"`   // Licensed device count, or UnlimitedDevices
+	ConnectionID int            `json:"connection_id"`
+	Interface    string         `json:"interface"`
+	SerialNumber string         `json:"serial_number"`
+	EasyFind     EasyFindStatus `json:"easy_find"`
+	Raw          ServerInfo     `json:"raw"` // Fields as reported
+}
+
+// EasyFindStatus is the EasyFind state of a server
+type EasyFindStatus struct {
+	Enabled bool   `json:"enabled"`
+	Detail  string `json:"detail"` // Text following "enabled", e.g. an EasyFind address
+}
+
+// Unlimited reports whether the server accepts any number of devices
+func (d *ServerDetails) Unlimited() bool {
+	return d.MaxDevices == UnlimitedDevices
+}
+
+// ServerDetails returns the typed information about a server
+func (c *Client) ServerDetails(serverName string) (*ServerDetails, error) {
+	return c.ServerDetailsContext(context.Background(), serverName)
+}
+
+// ServerDetailsContext is like ServerDetails but uses ctx to cancel or time out the command
+func (c *Client) ServerDetailsContext(ctx context.Context, serverName string) (*ServerDetails, error) {
+	info, err := c.ServerInfoContext(ctx, serverName)
+	if err != nil {
+		return nil, err
+	}
+	return info.Details()
+}
+
+// Details converts the raw fields into their typed form. Empty fields are left
+// at their zero value; fields that cannot be parsed are reported as
+// ErrInvalidResponse, with the remaining fields still filled in.
+func (i *ServerInfo) Details() (*ServerDetails, error) {
+	details := &ServerDetails{
+		Name:         i.Name,
+		State:        i.State,
+		Interface:    i.Interface,
+		SerialNumber: i.SerialNumber,
+		Raw:          *i,
+	}
+
+	var errs []error
+	field := func(name, value string, parse func(string) error) {
+		if value == "" {
+			return
+		}
+		if err := parse(value); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %v", ErrInvalidResponse, name, err))
+		}
+	}
+
+	field("VERSION", i.Version, func(s string) (err error) {
+		details.Version, err = ParseVersion(s)
+		return err
+	})
+	field("ADDRESS", i.Address, func(s string) error {
+		details.Hostname, details.IP = parseServerAddress(s)
+		return nil
+	})
+	field("PORT", i.Port, func(s string) (err error) {
+		details.Port, err = strconv.Atoi(s)
+		return err
+	})
+	field("CONNECTED FOR", i.ConnectedFor, func(s string) (err error) {
+		details.ConnectedFor, err = parseConnectedFor(s)
+		return err
+	})
+	field("MAX DEVICES", i.MaxDevices, func(s string) (err error) {
+		details.MaxDevices, err = parseMaxDevices(s)
+		return err
+	})
+	field("CONNECTION ID", i.ConnectionID, func(s string) (err error) {
+		details.ConnectionID, err = strconv.Atoi(s)
+		return err
+	})
+	field("EASYFIND", i.EasyFind, func(s string) (err error) {
+		details.EasyFind, err = parseEasyFind(s)
+		return err
+	})
+
+	return details, errors.Join(errs...)
+}
+
+// parseServerAddress splits "hostname (ip)" into its parts. A lone value is
+// taken as the IP address too if it is one.
+func parseServerAddress(s string) (hostname, ip string) {
+	if group, rest, ok := cutTrailingGroup(s); ok {
+		return rest, group
+	}
+	if net.ParseIP(s) != nil {
+		return s, s
+	}
+	return s, ""
+}
+
+// parseConnectedFor parses durations such as "9265 sec" or "3 min"
+func parseConnectedFor(s string) (time.Duration, error) {
+	number, unit, _ := strings.Cut(strings.TrimSpace(s), " ")
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "", "s", "sec", "secs", "second", "seconds":
+		return time.Duration(n) * time.Second, nil
+	case "min", "mins", "minute", "minutes":
+		return time.Duration(n) * time.Minute, nil
+	case "hour", "hours":
+		return time.Duration(n) * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("invalid duration %q: unknown unit %q", s, unit)
+	}
+}
+
+// parseMaxDevices parses a device count or "unlimited"
+func parseMaxDevices(s string) (int, error) {
+	if strings.EqualFold(s, "unlimited") {
+		return UnlimitedDevices, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// parseEasyFind parses "not enabled", "enabled" or "enabled <detail>"
+func parseEasyFind(s string) (EasyFindStatus, error) {
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasPrefix(lower, "not enabled"), strings.HasPrefix(lower, "disabled"):
+		return EasyFindStatus{}, nil
+	case strings.HasPrefix(lower, "enabled"):
+		detail := strings.Trim(s[len("enabled"):], " :,()")
+		return EasyFindStatus{Enabled: true, Detail: detail}, nil
+	default:
+		return EasyFindStatus{}, fmt.Errorf("unknown status %q", s)
+	}
+}
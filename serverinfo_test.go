@@ -0,0 +1,159 @@
+package virtualhere
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerInfoDetails(t *testing.T) {
+	info := ServerInfo{
+		Name:         "Raspberry Hub",
+		Version:      "4.3.1",
+		State:        "Logged in",
+		Address:      "raspberrypi (192.168.1.10)",
+		Port:         "7575",
+		ConnectedFor: "9265 sec",
+		MaxDevices:   "unlimited",
+		ConnectionID: "4",
+		Interface:    "eth0",
+		SerialNumber: "b827eb1a2b3c",
+		EasyFind:     "enabled (b827eb1a2b3c.easyfind)",
+	}
+	details, err := info.Details()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ServerDetails{
+		Name:         "Raspberry Hub",
+		Version:      Version{Major: 4, Minor: 3, Revision: 1},
+		State:        "Logged in",
+		Hostname:     "raspberrypi",
+		IP:           "192.168.1.10",
+		Port:         7575,
+		ConnectedFor: 9265 * time.Second,
+		MaxDevices:   UnlimitedDevices,
+		ConnectionID: 4,
+		Interface:    "eth0",
+		SerialNumber: "b827eb1a2b3c",
+		EasyFind:     EasyFindStatus{Enabled: true, Detail: "b827eb1a2b3c.easyfind"},
+		Raw:          info,
+	}
+	if !reflect.DeepEqual(details, want) {
+		t.Errorf("Details() = %+v, want %+v", details, want)
+	}
+	if !details.Unlimited() {
+		t.Error("Unlimited() = false for MAX DEVICES unlimited")
+	}
+}
+
+func TestServerInfoDetailsInvalid(t *testing.T) {
+	info := ServerInfo{Name: "Hub", Port: "seventy", MaxDevices: "3", ConnectedFor: "5 fortnights"}
+	details, err := info.Details()
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("got %v, want ErrInvalidResponse", err)
+	}
+	for _, field := range []string{"PORT", "CONNECTED FOR"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("error %q does not name %s", err, field)
+		}
+	}
+
+	// The fields that parse are still filled in
+	if details.Name != "Hub" || details.MaxDevices != 3 || details.Unlimited() {
+		t.Errorf("Details() = %+v", details)
+	}
+}
+
+func TestParseServerAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		hostname string
+		ip       string
+	}{
+		{"raspberrypi (192.168.1.10)", "raspberrypi", "192.168.1.10"},
+		{"hub.local (fe80::1)", "hub.local", "fe80::1"},
+		{"192.168.1.10", "192.168.1.10", "192.168.1.10"},
+		{"::1", "::1", "::1"},
+		{"raspberrypi", "raspberrypi", ""},
+	}
+
+	for _, tt := range tests {
+		hostname, ip := parseServerAddress(tt.address)
+		if hostname != tt.hostname || ip != tt.ip {
+			t.Errorf("parseServerAddress(%q) = %q, %q, want %q, %q", tt.address, hostname, ip, tt.hostname, tt.ip)
+		}
+	}
+}
+
+func TestParseConnectedFor(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"9265 sec", 9265 * time.Second, false},
+		{"42", 42 * time.Second, false},
+		{"1 second", time.Second, false},
+		{"30 s", 30 * time.Second, false},
+		{"3 min", 3 * time.Minute, false},
+		{"2 Minutes", 2 * time.Minute, false},
+		{"5 hours", 5 * time.Hour, false},
+		{" 7 secs ", 7 * time.Second, false},
+		{"5 days", 0, true},
+		{"sec", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseConnectedFor(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseConnectedFor(%q) = %v, %v, want %v (error %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseMaxDevices(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"unlimited", UnlimitedDevices, false},
+		{"Unlimited", UnlimitedDevices, false},
+		{"1", 1, false},
+		{"many", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMaxDevices(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseMaxDevices(%q) = %d, %v, want %d (error %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseEasyFind(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    EasyFindStatus
+		wantErr bool
+	}{
+		{"not enabled", EasyFindStatus{}, false},
+		{"Disabled", EasyFindStatus{}, false},
+		{"enabled", EasyFindStatus{Enabled: true}, false},
+		{"Enabled: b827eb1a2b3c.easyfind", EasyFindStatus{Enabled: true, Detail: "b827eb1a2b3c.easyfind"}, false},
+		{"enabled (b827eb1a2b3c.easyfind)", EasyFindStatus{Enabled: true, Detail: "b827eb1a2b3c.easyfind"}, false},
+		{"pending", EasyFindStatus{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseEasyFind(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseEasyFind(%q) = %+v, %v, want %+v (error %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package virtualhere
+
+import (
+	"context"
+	"fmt"
+)
+
+// AutoUseScope selects which auto-use rule SetAutoUse changes
+type AutoUseScope int
+
+const (
+	AutoUseScopeHub        AutoUseScope = iota // Every device on a hub (AUTO USE HUB)
+	AutoUseScopePort                           // Any device on the port of a device (AUTO USE PORT)
+	AutoUseScopeDevice                         // A device on any port (AUTO USE DEVICE)
+	AutoUseScopeDevicePort                     // A device on its current port (AUTO USE DEVICE PORT)
+)
+
+// String returns the name of the scope, e.g. "device-port"
+func (s AutoUseScope) String() string {
+	switch s {
+	case AutoUseScopeHub:
+		return "hub"
+	case AutoUseScopePort:
+		return "port"
+	case AutoUseScopeDevice:
+		return "device"
+	case AutoUseScopeDevicePort:
+		return "device-port"
+	default:
+		return fmt.Sprintf("scope(%d)", int(s))
+	}
+}
+
+// verb returns the toggle command of the scope
+func (s AutoUseScope) verb() string {
+	switch s {
+	case AutoUseScopeHub:
+		return "AUTO USE HUB"
+	case AutoUseScopePort:
+		return "AUTO USE PORT"
+	case AutoUseScopeDevice:
+		return "AUTO USE DEVICE"
+	case AutoUseScopeDevicePort:
+		return "AUTO USE DEVICE PORT"
+	default:
+		return ""
+	}
+}
+
+// mode returns the autoUse attribute reported for devices covered by the scope
+func (s AutoUseScope) mode() AutoUseMode {
+	switch s {
+	case AutoUseScopeHub:
+		return AutoUseModeHub
+	case AutoUseScopePort:
+		return AutoUseModePort
+	case AutoUseScopeDevice:
+		return AutoUseModeDevice
+	case AutoUseScopeDevicePort:
+		return AutoUseModeDevicePort
+	default:
+		return ""
+	}
+}
+
+// SetAutoUse enables or disables the auto-use rule of scope for target, which
+// is a server name or address for AutoUseScopeHub and a device address such as
+// "raspberrypi.114" otherwise. Unlike the AutoUse* toggles it is idempotent: the
+// current rule is read from GET CLIENT STATE, the toggle is only sent if needed,
+// and the result is verified. An error matching ErrStateMismatch is returned if
+// the client did not reach the requested state.
+//
+// Hub auto-use is only observable through the hub's devices that have no rule
+// of their own, so it cannot be set on a hub without such devices.
+func (c *Client) SetAutoUse(target string, scope AutoUseScope, enabled bool) error {
+	return c.SetAutoUseContext(context.Background(), target, scope, enabled)
+}
+
+// SetAutoUseContext is like SetAutoUse but uses ctx to cancel or time out the commands
+func (c *Client) SetAutoUseContext(ctx context.Context, target string, scope AutoUseScope, enabled bool) error {
+	verb := scope.verb()
+	if verb == "" {
+		return fmt.Errorf("%w: unknown auto-use scope %d", ErrInvalidArgument, int(scope))
+	}
+
+	current, err := c.autoUseEnabled(ctx, target, scope)
+	if err != nil {
+		return err
+	}
+	if current == enabled {
+		return nil
+	}
+
+	result, err := c.execute(ctx, verb, target)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return result.Error
+	}
+
+	current, err = c.autoUseEnabled(ctx, target, scope)
+	if err != nil {
+		return err
+	}
+	if current != enabled {
+		return fmt.Errorf("%w: %s auto-use of %s is %s after %s, want %s", ErrStateMismatch, scope, target, onOff(current), verb, onOff(enabled))
+	}
+	return nil
+}
+
+// autoUseEnabled reports whether the auto-use rule of scope is set for target
+func (c *Client) autoUseEnabled(ctx context.Context, target string, scope AutoUseScope) (bool, error) {
+	state, err := c.GetClientStateContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if scope == AutoUseScopeHub {
+		server := state.findServer(target)
+		if server == nil {
+			return false, fmt.Errorf("%w: %q", ErrServerNotFound, target)
+		}
+		return hubAutoUseEnabled(target, server.Devices)
+	}
+
+	for _, device := range state.Devices() {
+		if device.Address.String() == target {
+			return device.AutoUse == scope.mode(), nil
+		}
+	}
+	return false, fmt.Errorf("%w: %q", ErrDeviceNotFound, target)
+}
+
+// hubAutoUseEnabled infers the hub auto-use rule from the devices of a hub.
+// Devices with a rule of their own (port, device, off) report that rule
+// instead, so only devices reporting auto-use-hub or not-set are considered,
+// and they must agree.
+func hubAutoUseEnabled(target string, devices []XMLDevice) (bool, error) {
+	var on, off int
+	for _, device := range devices {
+		switch device.AutoUse {
+		case AutoUseModeHub:
+			on++
+		case AutoUseNotSet, "":
+			off++
+		}
+	}
+
+	switch {
+	case on == 0 && off == 0:
+		return false, fmt.Errorf("cannot determine hub auto-use of %q: no device without a rule of its own", target)
+	case on > 0 && off > 0:
+		return false, fmt.Errorf("cannot determine hub auto-use of %q: %d device(s) report auto-use-hub and %d not-set", target, on, off)
+	default:
+		return on > 0, nil
+	}
+}
+
+// SetAutoFind enables or disables finding hubs via Bonjour. Unlike AutoFind it
+// is idempotent: the current setting is read from LIST, the toggle is only sent
+// if needed, and the result is verified.
+func (c *Client) SetAutoFind(enabled bool) error {
+	return c.SetAutoFindContext(context.Background(), enabled)
+}
+
+// SetAutoFindContext is like SetAutoFind but uses ctx to cancel or time out the commands
+func (c *Client) SetAutoFindContext(ctx context.Context, enabled bool) error {
+	return c.setListSetting(ctx, "AUTOFIND", enabled, func(s *ClientState) bool { return s.AutoFindEnabled })
+}
+
+// SetReverseLookup enables or disables reverse lookup of client hostnames.
+// Unlike Reverse it is idempotent, see SetAutoFind.
+func (c *Client) SetReverseLookup(enabled bool) error {
+	return c.SetReverseLookupContext(context.Background(), enabled)
+}
+
+// SetReverseLookupContext is like SetReverseLookup but uses ctx to cancel or time out the commands
+func (c *Client) SetReverseLookupContext(ctx context.Context, enabled bool) error {
+	return c.setListSetting(ctx, "REVERSE", enabled, func(s *ClientState) bool { return s.ReverseLookup })
+}
+
+// SetSSLReverse enables or disables reverse SSL lookup. Unlike SSLReverse it
+// is idempotent, see SetAutoFind.
+func (c *Client) SetSSLReverse(enabled bool) error {
+	return c.SetSSLReverseContext(context.Background(), enabled)
+}
+
+// SetSSLReverseContext is like SetSSLReverse but uses ctx to cancel or time out the commands
+func (c *Client) SetSSLReverseContext(ctx context.Context, enabled bool) error {
+	return c.setListSetting(ctx, "SSLREVERSE", enabled, func(s *ClientState) bool { return s.SSLReverseLookup })
+}
+
+// SetAutoUseAll enables or disables auto-use of all devices. Unlike
+// AutoUseAll it is idempotent, see SetAutoFind.
+func (c *Client) SetAutoUseAll(enabled bool) error {
+	return c.SetAutoUseAllContext(context.Background(), enabled)
+}
+
+// SetAutoUseAllContext is like SetAutoUseAll but uses ctx to cancel or time out the commands
+func (c *Client) SetAutoUseAllContext(ctx context.Context, enabled bool) error {
+	return c.setListSetting(ctx, "AUTO USE ALL", enabled, func(s *ClientState) bool { return s.AutoUseAllEnabled })
+}
+
+// setListSetting sends the toggle verb if the setting reported by LIST and
+// read by get differs from enabled, then verifies the new value
+func (c *Client) setListSetting(ctx context.Context, verb string, enabled bool, get func(*ClientState) bool) error {
+	state, err := c.ListContext(ctx)
+	if err != nil {
+		return err
+	}
+	if get(state) == enabled {
+		return nil
+	}
+
+	result, err := c.execute(ctx, verb)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return result.Error
+	}
+
+	state, err = c.ListContext(ctx)
+	if err != nil {
+		return err
+	}
+	if get(state) != enabled {
+		return fmt.Errorf("%w: setting is %s after %s, want %s", ErrStateMismatch, onOff(!enabled), verb, onOff(enabled))
+	}
+	return nil
+}
+
+// findServer returns the server whose name, hostname or address is key
+func (s *XMLClientState) findServer(key string) *XMLServer {
+	for i := range s.Servers {
+		server := &s.Servers[i]
+		ref := server.hubRef()
+		if key == ref.Name || key == ref.Hostname || key == ref.Address.Host || key == ref.Address.String() {
+			return server
+		}
+	}
+	return nil
+}
+
+// onOff formats a setting the way the VirtualHere client reports it
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,172 @@
+//go:build !windows
+// +build !windows
+
+package virtualhere_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tryanks/virtualhere-go"
+	"github.com/Tryanks/virtualhere-go/vhtest"
+)
+
+// autoUseModes returns the auto-use mode of every device by address
+func autoUseModes(t *testing.T, client *virtualhere.Client) map[string]virtualhere.AutoUseMode {
+	t.Helper()
+	devices, err := client.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes := make(map[string]virtualhere.AutoUseMode)
+	for _, device := range devices {
+		modes[device.Address.String()] = device.AutoUse
+	}
+	return modes
+}
+
+// countCommands returns how many commands received by srv start with prefix
+func countCommands(srv *vhtest.Server, prefix string) int {
+	n := 0
+	for _, command := range srv.Commands() {
+		if strings.HasPrefix(command, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSetAutoUseHubWithDeviceRule(t *testing.T) {
+	srv := newServer(t)
+	srv.AddHub(vhtest.Hub{Name: "Raspberry Hub", Address: "raspberrypi:7575", AutoUse: true})
+	srv.AddDevice("raspberrypi:7575", vhtest.Device{Address: 1, Product: "Disk"})
+	srv.AddDevice("raspberrypi:7575", vhtest.Device{Address: 2, Product: "FT232R", AutoUse: virtualhere.AutoUseModeDevice})
+	client, err := srv.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Already on: the device with its own rule must not make it look off
+	if err := client.SetAutoUse("Raspberry Hub", virtualhere.AutoUseScopeHub, true); err != nil {
+		t.Fatal(err)
+	}
+	if n := countCommands(srv, "AUTO USE HUB"); n != 0 {
+		t.Fatalf("sent AUTO USE HUB %d time(s) for a hub rule that was already on", n)
+	}
+
+	if err := client.SetAutoUse("Raspberry Hub", virtualhere.AutoUseScopeHub, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := countCommands(srv, "AUTO USE HUB"); n != 1 {
+		t.Fatalf("sent AUTO USE HUB %d time(s), want 1", n)
+	}
+	modes := autoUseModes(t, client)
+	if modes["raspberrypi.1"] != virtualhere.AutoUseNotSet || modes["raspberrypi.2"] != virtualhere.AutoUseModeDevice {
+		t.Fatalf("auto-use after disabling the hub rule = %v", modes)
+	}
+
+	if err := client.SetAutoUse("Raspberry Hub", virtualhere.AutoUseScopeHub, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetAutoUse("Raspberry Hub", virtualhere.AutoUseScopeHub, true); err != nil {
+		t.Fatal(err)
+	}
+	if n := countCommands(srv, "AUTO USE HUB"); n != 2 {
+		t.Fatalf("sent AUTO USE HUB %d time(s), want 2", n)
+	}
+	modes = autoUseModes(t, client)
+	if modes["raspberrypi.1"] != virtualhere.AutoUseModeHub || modes["raspberrypi.2"] != virtualhere.AutoUseModeDevice {
+		t.Fatalf("auto-use after enabling the hub rule = %v", modes)
+	}
+}
+
+func TestSetAutoUseHubUndeterminable(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices []vhtest.Device
+	}{
+		{"no devices", nil},
+		{"only devices with their own rule", []vhtest.Device{{Address: 1, AutoUse: virtualhere.AutoUseModePort}}},
+		{"devices disagree", []vhtest.Device{{Address: 1, AutoUse: virtualhere.AutoUseModeHub}, {Address: 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t)
+			srv.AddHub(vhtest.Hub{Name: "Raspberry Hub", Address: "raspberrypi:7575", Devices: tt.devices})
+			client, err := srv.Client()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.SetAutoUse("Raspberry Hub", virtualhere.AutoUseScopeHub, false)
+			if err == nil || !strings.Contains(err.Error(), "cannot determine") {
+				t.Fatalf("SetAutoUse returned %v, want a cannot determine error", err)
+			}
+			if n := countCommands(srv, "AUTO USE HUB"); n != 0 {
+				t.Fatalf("sent AUTO USE HUB %d time(s)", n)
+			}
+		})
+	}
+}
+
+func TestSetAutoUseDevice(t *testing.T) {
+	srv := newServer(t)
+	srv.AddHub(vhtest.Hub{Name: "Raspberry Hub", Address: "raspberrypi:7575"})
+	srv.AddDevice("raspberrypi:7575", vhtest.Device{Address: 114, Product: "FT232R"})
+	client, err := srv.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if err := client.SetAutoUse("raspberrypi.114", virtualhere.AutoUseScopeDevice, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countCommands(srv, "AUTO USE DEVICE,"); n != 1 {
+		t.Fatalf("sent AUTO USE DEVICE %d time(s), want 1", n)
+	}
+	if mode := autoUseModes(t, client)["raspberrypi.114"]; mode != virtualhere.AutoUseModeDevice {
+		t.Fatalf("auto-use = %q, want auto-use-device", mode)
+	}
+
+	if err := client.SetAutoUse("raspberrypi.999", virtualhere.AutoUseScopeDevice, true); !errors.Is(err, virtualhere.ErrDeviceNotFound) {
+		t.Fatalf("SetAutoUse of a missing device returned %v, want ErrDeviceNotFound", err)
+	}
+
+	// A daemon that acknowledges the toggle without applying it
+	srv.SetResponse("AUTO USE DEVICE", "OK")
+	if err := client.SetAutoUse("raspberrypi.114", virtualhere.AutoUseScopeDevice, false); !errors.Is(err, virtualhere.ErrStateMismatch) {
+		t.Fatalf("SetAutoUse returned %v, want ErrStateMismatch", err)
+	}
+}
+
+func TestSetAutoUseAll(t *testing.T) {
+	srv := newServer(t)
+	client, err := srv.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// AUTO USE ALL toggles, so setting it twice sends it once
+	for range 2 {
+		if err := client.SetAutoUseAll(true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countCommands(srv, "AUTO USE ALL"); n != 1 {
+		t.Fatalf("sent AUTO USE ALL %d time(s), want 1", n)
+	}
+	if !srv.Settings().AutoUseAll {
+		t.Fatal("auto-use all is off after SetAutoUseAll(true)")
+	}
+
+	if err := client.SetAutoUseAll(false); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Settings().AutoUseAll {
+		t.Fatal("auto-use all is on after SetAutoUseAll(false)")
+	}
+}
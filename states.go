@@ -0,0 +1,225 @@
+package virtualhere
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DeviceState is the state attribute of a device in GET CLIENT STATE
+type DeviceState int
+
+const (
+	DeviceStateUnknown DeviceState = 0 // Not reported, e.g. for devices built from LIST
+	DeviceAvailable    DeviceState = 1 // Not in use by any client
+	DeviceInUseByOther DeviceState = 2 // In use by another client
+	DeviceInUseByMe    DeviceState = 3 // In use by this client
+)
+
+var deviceStateNames = map[DeviceState]string{
+	DeviceStateUnknown: "unknown",
+	DeviceAvailable:    "available",
+	DeviceInUseByOther: "in-use-by-other",
+	DeviceInUseByMe:    "in-use-by-me",
+}
+
+// String returns the name of the state, e.g. "in-use-by-me"
+func (s DeviceState) String() string {
+	if name, ok := deviceStateNames[s]; ok {
+		return name
+	}
+	return "state(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Available reports whether no client is using the device
+func (s DeviceState) Available() bool {
+	return s == DeviceAvailable
+}
+
+// InUse reports whether any client is using the device
+func (s DeviceState) InUse() bool {
+	return s == DeviceInUseByMe || s == DeviceInUseByOther
+}
+
+// InUseByMe reports whether this client is using the device
+func (s DeviceState) InUseByMe() bool {
+	return s == DeviceInUseByMe
+}
+
+// InUseByOther reports whether another client is using the device
+func (s DeviceState) InUseByOther() bool {
+	return s == DeviceInUseByOther
+}
+
+// MarshalText implements encoding.TextMarshaler using the state's name
+func (s DeviceState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting names and numbers
+func (s *DeviceState) UnmarshalText(text []byte) error {
+	value, err := parseEnum(string(text), deviceStateNames)
+	if err != nil {
+		return fmt.Errorf("invalid device state: %w", err)
+	}
+	*s = value
+	return nil
+}
+
+// MarshalJSON encodes the state as its number, keeping JSON output compatible
+// with the plain int the field used to be; use String for the name
+func (s DeviceState) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Itoa(int(s))), nil
+}
+
+// UnmarshalJSON decodes a number, or a name as produced by MarshalText
+func (s *DeviceState) UnmarshalJSON(data []byte) error {
+	if text, err := strconv.Unquote(string(data)); err == nil {
+		return s.UnmarshalText([]byte(text))
+	}
+	number, err := strconv.Atoi(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid device state %s", data)
+	}
+	*s = DeviceState(number)
+	return nil
+}
+
+// MarshalXMLAttr encodes the state numerically, as the VirtualHere client does
+func (s DeviceState) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: strconv.Itoa(int(s))}, nil
+}
+
+// UnmarshalXMLAttr decodes the numeric state attribute
+func (s *DeviceState) UnmarshalXMLAttr(attr xml.Attr) error {
+	return s.UnmarshalText([]byte(attr.Value))
+}
+
+// ConnectionState is the state attribute of a server connection in GET CLIENT STATE
+type ConnectionState int
+
+const (
+	ConnectionDisconnected ConnectionState = 0 // Not connected
+	ConnectionConnecting   ConnectionState = 1 // Connection or login in progress
+	ConnectionConnected    ConnectionState = 2 // Connected and logged in
+)
+
+var connectionStateNames = map[ConnectionState]string{
+	ConnectionDisconnected: "disconnected",
+	ConnectionConnecting:   "connecting",
+	ConnectionConnected:    "connected",
+}
+
+// String returns the name of the state, e.g. "connected"
+func (s ConnectionState) String() string {
+	if name, ok := connectionStateNames[s]; ok {
+		return name
+	}
+	return "state(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Connected reports whether the server connection is established
+func (s ConnectionState) Connected() bool {
+	return s == ConnectionConnected
+}
+
+// MarshalText implements encoding.TextMarshaler using the state's name
+func (s ConnectionState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting names and numbers
+func (s *ConnectionState) UnmarshalText(text []byte) error {
+	value, err := parseEnum(string(text), connectionStateNames)
+	if err != nil {
+		return fmt.Errorf("invalid connection state: %w", err)
+	}
+	*s = value
+	return nil
+}
+
+// MarshalJSON encodes the state as its number, keeping JSON output compatible
+// with the plain int the field used to be; use String for the name
+func (s ConnectionState) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Itoa(int(s))), nil
+}
+
+// UnmarshalJSON decodes a number, or a name as produced by MarshalText
+func (s *ConnectionState) UnmarshalJSON(data []byte) error {
+	if text, err := strconv.Unquote(string(data)); err == nil {
+		return s.UnmarshalText([]byte(text))
+	}
+	number, err := strconv.Atoi(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid connection state %s", data)
+	}
+	*s = ConnectionState(number)
+	return nil
+}
+
+// MarshalXMLAttr encodes the state numerically, as the VirtualHere client does
+func (s ConnectionState) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: strconv.Itoa(int(s))}, nil
+}
+
+// UnmarshalXMLAttr decodes the numeric state attribute
+func (s *ConnectionState) UnmarshalXMLAttr(attr xml.Attr) error {
+	return s.UnmarshalText([]byte(attr.Value))
+}
+
+// parseEnum decodes an integer enum from its name or its decimal value
+func parseEnum[T ~int](text string, names map[T]string) (T, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	for value, name := range names {
+		if strings.EqualFold(text, name) {
+			return value, nil
+		}
+	}
+	number, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("%q is neither a known name nor a number", text)
+	}
+	return T(number), nil
+}
+
+// AutoUseMode is the autoUse attribute of a device in GET CLIENT STATE.
+// Values other than the constants below are preserved as reported.
+type AutoUseMode string
+
+const (
+	AutoUseNotSet         AutoUseMode = "not-set"              // No auto-use rule applies
+	AutoUseOff            AutoUseMode = "off"                  // Auto-use explicitly disabled
+	AutoUseOn             AutoUseMode = "on"                   // Auto-use enabled, scope unknown (e.g. the "*" marker of LIST)
+	AutoUseModeHub        AutoUseMode = "auto-use-hub"         // Enabled for all devices on the hub
+	AutoUseModePort       AutoUseMode = "auto-use-port"        // Enabled for any device on this port
+	AutoUseModeDevice     AutoUseMode = "auto-use-device"      // Enabled for this device on any port
+	AutoUseModeDevicePort AutoUseMode = "auto-use-device-port" // Enabled for this device on this port
+)
+
+// String returns the mode as reported, or "not-set" if empty
+func (m AutoUseMode) String() string {
+	if m == "" {
+		return string(AutoUseNotSet)
+	}
+	return string(m)
+}
+
+// Enabled reports whether the device will be used automatically
+func (m AutoUseMode) Enabled() bool {
+	return m != "" && m != AutoUseNotSet && m != AutoUseOff
+}
+
+// MarshalText implements encoding.TextMarshaler, keeping the mode as reported
+func (m AutoUseMode) MarshalText() ([]byte, error) {
+	return []byte(m), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (m *AutoUseMode) UnmarshalText(text []byte) error {
+	*m = AutoUseMode(strings.TrimSpace(string(text)))
+	return nil
+}
@@ -0,0 +1,116 @@
+package virtualhere
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestStatesFromXML(t *testing.T) {
+	const output = `<state>
+<server>
+<connection connectionId="1" serverName="Raspberry Hub" hostname="raspberrypi" state="2" host="raspberrypi" port="7575"/>
+<device vendor="FTDI" product="FT232R" address="114" state="3" autoUse="auto-use-device"/>
+<device vendor="FTDI" product="FT232R" address="115" state="2" autoUse="not-set"/>
+<device vendor="FTDI" product="FT232R" address="116" state="1"/>
+<device vendor="FTDI" product="FT232R" address="117" state="9" autoUse="auto-use-future"/>
+</server>
+</state>`
+
+	var state XMLClientState
+	if err := xml.Unmarshal([]byte(output), &state); err != nil {
+		t.Fatal(err)
+	}
+
+	if conn := state.Servers[0].Connection; conn.State != ConnectionConnected || !conn.State.Connected() {
+		t.Errorf("connection state = %v, want connected", conn.State)
+	}
+
+	tests := []struct {
+		state   DeviceState
+		name    string
+		autoUse AutoUseMode
+		enabled bool
+	}{
+		{DeviceInUseByMe, "in-use-by-me", AutoUseModeDevice, true},
+		{DeviceInUseByOther, "in-use-by-other", AutoUseNotSet, false},
+		{DeviceAvailable, "available", "", false},
+		{DeviceState(9), "state(9)", "auto-use-future", true},
+	}
+	for i, tt := range tests {
+		device := state.Servers[0].Devices[i]
+		if device.State != tt.state || device.State.String() != tt.name {
+			t.Errorf("device %d state = %d %q, want %d %q", device.Address, device.State, device.State, tt.state, tt.name)
+		}
+		if device.AutoUse != tt.autoUse || device.AutoUse.Enabled() != tt.enabled {
+			t.Errorf("device %d auto-use = %q (enabled %v), want %q (enabled %v)", device.Address, device.AutoUse, device.AutoUse.Enabled(), tt.autoUse, tt.enabled)
+		}
+	}
+
+	device := state.Servers[0].Devices[0]
+	if !device.State.InUseByMe() || !device.State.InUse() || device.State.InUseByOther() || device.State.Available() {
+		t.Errorf("helpers of %v disagree", device.State)
+	}
+}
+
+func TestStatesJSONIsNumeric(t *testing.T) {
+	device := XMLDevice{Address: 114, State: DeviceInUseByMe, AutoUse: AutoUseModeDevice}
+	data, err := json.Marshal(device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"state":3`, `"auto_use":"auto-use-device"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON %s does not contain %s", data, want)
+		}
+	}
+
+	conn := XMLServerConnection{State: ConnectionConnected}
+	data, err = json.Marshal(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"state":2`) {
+		t.Errorf("JSON %s does not contain \"state\":2", data)
+	}
+
+	var decoded XMLDevice
+	if err := json.Unmarshal([]byte(`{"state":2,"auto_use":"auto-use-hub"}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.State != DeviceInUseByOther || decoded.AutoUse != AutoUseModeHub {
+		t.Errorf("decoded %v %q", decoded.State, decoded.AutoUse)
+	}
+	if err := json.Unmarshal([]byte(`{"state":"available"}`), &decoded); err != nil || decoded.State != DeviceAvailable {
+		t.Errorf("decoding a state name gave %v, %v", decoded.State, err)
+	}
+	if err := json.Unmarshal([]byte(`{"state":true}`), &decoded); err == nil {
+		t.Error("decoding a boolean state succeeded")
+	}
+}
+
+func TestStatesText(t *testing.T) {
+	for value, name := range deviceStateNames {
+		text, _ := value.MarshalText()
+		var decoded DeviceState
+		if string(text) != name || decoded.UnmarshalText(text) != nil || decoded != value {
+			t.Errorf("text round trip of %d gave %q, %d", value, text, decoded)
+		}
+	}
+	for value, name := range connectionStateNames {
+		text, _ := value.MarshalText()
+		var decoded ConnectionState
+		if string(text) != name || decoded.UnmarshalText(text) != nil || decoded != value {
+			t.Errorf("text round trip of %d gave %q, %d", value, text, decoded)
+		}
+	}
+
+	var state DeviceState
+	if err := state.UnmarshalText([]byte("In-Use-By-Other")); err != nil || state != DeviceInUseByOther {
+		t.Errorf("UnmarshalText ignores case: got %v, %v", state, err)
+	}
+	if err := state.UnmarshalText([]byte("held")); err == nil {
+		t.Error("UnmarshalText accepted an unknown name")
+	}
+}
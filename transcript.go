@@ -0,0 +1,163 @@
+package virtualhere
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrTranscriptMismatch is returned by a ReplayTransport when a command does
+// not match the next entry of its transcript
+var ErrTranscriptMismatch = errors.New("command does not match transcript")
+
+// TranscriptEntry is a single recorded command/response exchange.
+// Transcripts are stored as one JSON encoded entry per line.
+type TranscriptEntry struct {
+	Time     time.Time     `json:"time"`
+	Command  string        `json:"command"`
+	Response string        `json:"response"`
+	Error    string        `json:"error,omitempty"`    // Transport error, if the exchange failed
+	Duration time.Duration `json:"duration,omitempty"` // Time the exchange took
+}
+
+// RecordingTransport wraps a Transport and appends every exchange to a transcript
+type RecordingTransport struct {
+	next Transport
+	mu   sync.Mutex
+	enc  *json.Encoder
+	err  error
+}
+
+// NewRecordingTransport returns a Transport that sends commands through next
+// and writes each exchange to w as a TranscriptEntry
+func NewRecordingTransport(next Transport, w io.Writer) *RecordingTransport {
+	return &RecordingTransport{
+		next: next,
+		enc:  json.NewEncoder(w),
+	}
+}
+
+// Send forwards command to the wrapped transport and records the exchange.
+// Recording failures do not affect the exchange; see Err.
+func (t *RecordingTransport) Send(ctx context.Context, command string) (string, error) {
+	start := time.Now()
+	response, err := t.next.Send(ctx, command)
+
+	// Passwords and license keys never reach the transcript
+	cmd := parseCommand(command)
+	entry := TranscriptEntry{
+		Time:     start,
+		Command:  cmd.Redacted(),
+		Response: redactOutput(cmd, response),
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		entry.Error = redactOutput(cmd, err.Error())
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if encErr := t.enc.Encode(entry); encErr != nil && t.err == nil {
+		t.err = fmt.Errorf("failed to record transcript: %w", encErr)
+	}
+
+	return response, err
+}
+
+// Err returns the first error encountered while writing the transcript
+func (t *RecordingTransport) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// ReadTranscript reads the entries of a transcript written by a RecordingTransport
+func ReadTranscript(r io.Reader) ([]TranscriptEntry, error) {
+	entries := make([]TranscriptEntry, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry TranscriptEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid transcript entry on line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ReadTranscriptFile reads a transcript file written by a RecordingTransport
+func ReadTranscriptFile(path string) ([]TranscriptEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadTranscript(f)
+}
+
+// ReplayTransport serves recorded responses back in transcript order.
+// Each command must equal the command of the next entry (compared with secrets
+// redacted), which makes replays deterministic regressions of the recorded session.
+type ReplayTransport struct {
+	mu      sync.Mutex
+	entries []TranscriptEntry
+	next    int
+}
+
+// NewReplayTransport returns a Transport that replays entries
+func NewReplayTransport(entries []TranscriptEntry) *ReplayTransport {
+	return &ReplayTransport{entries: entries}
+}
+
+// Send returns the recorded response (or error) of the next entry
+func (t *ReplayTransport) Send(ctx context.Context, command string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.next >= len(t.entries) {
+		return "", fmt.Errorf("%w: unexpected command %q after end of transcript", ErrTranscriptMismatch, redactCommand(command))
+	}
+
+	// Recorded commands have their secrets redacted, so compare redacted forms
+	entry := t.entries[t.next]
+	if entry.Command != redactCommand(command) {
+		return "", fmt.Errorf("%w: entry %d expects %q, got %q", ErrTranscriptMismatch, t.next+1, entry.Command, redactCommand(command))
+	}
+	t.next++
+
+	if entry.Error != "" {
+		return entry.Response, errors.New(entry.Error)
+	}
+	return entry.Response, nil
+}
+
+// Remaining returns the number of entries not yet replayed
+func (t *ReplayTransport) Remaining() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.entries) - t.next
+}
@@ -0,0 +1,195 @@
+package virtualhere
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// scriptedTransport answers commands from a map and fails unknown ones
+func scriptedTransport(responses map[string]string) Transport {
+	return TransportFunc(func(ctx context.Context, command string) (string, error) {
+		response, ok := responses[command]
+		if !ok {
+			return "", errors.New("failed to connect to response socket: connection refused")
+		}
+		return response, nil
+	})
+}
+
+const transcriptList = "Raspberry Hub (raspberrypi:7575)\n   --> Ultra USB 3.0 (raspberrypi.114)\n"
+
+// record sends commands through a RecordingTransport and returns the transcript
+func record(t *testing.T, next Transport, commands ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	recorder := NewRecordingTransport(next, &buf)
+	for _, command := range commands {
+		recorder.Send(context.Background(), command)
+	}
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	transport := scriptedTransport(map[string]string{
+		"LIST":                        transcriptList,
+		"USE,raspberrypi.114,pa,ss":   "OK",
+		"DEVICE INFO,raspberrypi.114": "ERROR: device not found",
+		"LICENSE SERVER,S1,10,sig==":  "ERROR: key S1,10,sig== is invalid",
+	})
+	commands := []string{
+		"LIST",
+		"USE,raspberrypi.114,pa,ss",
+		"DEVICE INFO,raspberrypi.114",
+		"LICENSE SERVER,S1,10,sig==",
+		"STOP USING,raspberrypi.115",
+	}
+	transcript := record(t, transport, commands...)
+
+	for _, secret := range []string{"pa,ss", "sig=="} {
+		if bytes.Contains(transcript, []byte(secret)) {
+			t.Errorf("transcript contains secret %q:\n%s", secret, transcript)
+		}
+	}
+
+	entries, err := ReadTranscript(bytes.NewReader(transcript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TranscriptEntry{
+		{Command: "LIST", Response: transcriptList},
+		{Command: "USE,raspberrypi.114,<redacted>", Response: "OK"},
+		{Command: "DEVICE INFO,raspberrypi.114", Response: "ERROR: device not found"},
+		{Command: "LICENSE SERVER,<redacted>", Response: "ERROR: key <redacted> is invalid"},
+		{Command: "STOP USING,raspberrypi.115", Error: "failed to connect to response socket: connection refused"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("read %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Time.IsZero() {
+			t.Errorf("entry %d has no time", i+1)
+		}
+		entry.Time, entry.Duration = want[i].Time, want[i].Duration
+		if entry != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i+1, entry, want[i])
+		}
+	}
+
+	// Replaying the same commands, secrets included, yields the recording
+	replay := NewReplayTransport(entries)
+	for i, command := range commands {
+		response, err := replay.Send(context.Background(), command)
+		if response != want[i].Response {
+			t.Errorf("replayed %s: response %q, want %q", command, response, want[i].Response)
+		}
+		if (err == nil) != (want[i].Error == "") || (err != nil && err.Error() != want[i].Error) {
+			t.Errorf("replayed %s: error %v, want %q", command, err, want[i].Error)
+		}
+	}
+	if n := replay.Remaining(); n != 0 {
+		t.Errorf("Remaining() = %d after replaying every entry", n)
+	}
+}
+
+func TestReplayThroughClient(t *testing.T) {
+	transcript := record(t, scriptedTransport(map[string]string{"LIST": transcriptList}), "LIST")
+	entries, err := ReadTranscript(bytes.NewReader(transcript))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClientWithTransport(NewReplayTransport(entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := client.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Hubs) != 1 || len(state.Hubs[0].Devices) != 1 || state.Hubs[0].Devices[0].Address != "raspberrypi.114" {
+		t.Errorf("List() = %+v", state)
+	}
+}
+
+func TestReplayRedactedSecret(t *testing.T) {
+	entries := []TranscriptEntry{
+		{Command: "USE,raspberrypi.114,<redacted>", Response: "OK"},
+		{Command: "LICENSE SERVER,<redacted>", Response: "OK"},
+	}
+	replay := NewReplayTransport(entries)
+
+	// The secret itself was never recorded, so any value matches it
+	for _, command := range []string{"USE,raspberrypi.114,other,password", "LICENSE SERVER,S2,1,xyz"} {
+		if response, err := replay.Send(context.Background(), command); err != nil || response != "OK" {
+			t.Errorf("replayed %s: %q, %v", command, response, err)
+		}
+	}
+
+	// A command without the secret is a different command
+	replay = NewReplayTransport(entries)
+	_, err := replay.Send(context.Background(), "USE,raspberrypi.114")
+	if !errors.Is(err, ErrTranscriptMismatch) {
+		t.Errorf("USE without password returned %v, want ErrTranscriptMismatch", err)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	entries := []TranscriptEntry{{Command: "LIST", Response: transcriptList}}
+
+	tests := []struct {
+		name     string
+		commands []string
+		want     string // Text of the error returned for the last command
+	}{
+		{"different command", []string{"GET CLIENT STATE"}, `entry 1 expects "LIST", got "GET CLIENT STATE"`},
+		{"end of transcript", []string{"LIST", "LIST"}, `unexpected command "LIST" after end of transcript`},
+		{"secret in mismatch", []string{"LIST", "USE,raspberrypi.114,hunter2"}, `unexpected command "USE,raspberrypi.114,<redacted>"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay := NewReplayTransport(entries)
+			var err error
+			for _, command := range tt.commands {
+				_, err = replay.Send(context.Background(), command)
+			}
+			if !errors.Is(err, ErrTranscriptMismatch) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want ErrTranscriptMismatch containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayCancelled(t *testing.T) {
+	replay := NewReplayTransport([]TranscriptEntry{{Command: "LIST"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := replay.Send(ctx, "LIST"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Send with a cancelled context returned %v", err)
+	}
+	if n := replay.Remaining(); n != 1 {
+		t.Errorf("a cancelled Send consumed an entry: %d remaining", n)
+	}
+}
+
+func TestReadTranscript(t *testing.T) {
+	entries, err := ReadTranscript(strings.NewReader("{\"command\":\"LIST\",\"response\":\"x\"}\n\n{\"command\":\"HELP\"}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Command != "LIST" || entries[1].Command != "HELP" {
+		t.Errorf("ReadTranscript() = %+v", entries)
+	}
+
+	_, err = ReadTranscript(strings.NewReader("{\"command\":\"LIST\"}\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("malformed transcript returned %v, want an error on line 2", err)
+	}
+}
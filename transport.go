@@ -0,0 +1,61 @@
+package virtualhere
+
+import "context"
+
+// Default IPC endpoints used by the VirtualHere client
+const (
+	DefaultRequestSocket  = "/tmp/vhclient"
+	DefaultResponseSocket = "/tmp/vhclient_response"
+	DefaultPipePath       = `\\.\pipe\vhclient`
+)
+
+// Transport delivers a single command to the VirtualHere client and returns
+// its raw, untrimmed response. Implementations must honour ctx cancellation.
+type Transport interface {
+	Send(ctx context.Context, command string) (string, error)
+}
+
+// TransportFunc adapts an ordinary function to the Transport interface
+type TransportFunc func(ctx context.Context, command string) (string, error)
+
+// Send calls f(ctx, command)
+func (f TransportFunc) Send(ctx context.Context, command string) (string, error) {
+	return f(ctx, command)
+}
+
+// UnixSocketTransport talks to the VirtualHere client over its pair of Unix
+// domain sockets (Linux/macOS). Empty paths fall back to the defaults.
+type UnixSocketTransport struct {
+	RequestPath  string // socket commands are written to, e.g. "/tmp/vhclient"
+	ResponsePath string // socket responses are read from, e.g. "/tmp/vhclient_response"
+}
+
+// NamedPipeTransport talks to the VirtualHere client over its named pipe
+// (Windows). An empty path falls back to the default.
+type NamedPipeTransport struct {
+	Path string // e.g. `\\.\pipe\vhclient`
+}
+
+// requestPath returns the configured request socket or the default
+func (t *UnixSocketTransport) requestPath() string {
+	if t.RequestPath != "" {
+		return t.RequestPath
+	}
+	return DefaultRequestSocket
+}
+
+// responsePath returns the configured response socket or the default
+func (t *UnixSocketTransport) responsePath() string {
+	if t.ResponsePath != "" {
+		return t.ResponsePath
+	}
+	return DefaultResponseSocket
+}
+
+// path returns the configured pipe path or the default
+func (t *NamedPipeTransport) path() string {
+	if t.Path != "" {
+		return t.Path
+	}
+	return DefaultPipePath
+}
@@ -15,11 +15,13 @@ type CommandResult struct {
 
 // Device represents a USB device connected to a VirtualHere hub
 type Device struct {
-	Address  string `json:"address"`  // e.g., "raspberrypi.114"
-	Name     string `json:"name"`     // e.g., "Ultra USB 3.0"
-	AutoUse  bool   `json:"auto_use"` // Whether auto-use is enabled for this device
-	InUse    bool   `json:"in_use"`   // Whether the device is currently in use
-	Nickname string `json:"nickname"` // Custom nickname if set
+	Address   string `json:"address"`      // e.g., "raspberrypi.114"
+	Name      string `json:"name"`         // e.g., "Ultra USB 3.0"
+	AutoUse   bool   `json:"auto_use"`     // Whether auto-use is enabled for this device
+	InUse     bool   `json:"in_use"`       // Whether the device is currently in use
+	InUseByMe bool   `json:"in_use_by_me"` // Whether this client is using the device
+	Holder    string `json:"holder"`       // Who is using the device if another client, as listed
+	Nickname  string `json:"nickname"`     // Custom nickname if set
 }
 
 // Hub represents a VirtualHere USB server/hub
@@ -35,6 +37,7 @@ type ClientState struct {
 	AutoFindEnabled   bool  `json:"auto_find_enabled"`
 	AutoUseAllEnabled bool  `json:"auto_use_all_enabled"`
 	ReverseLookup     bool  `json:"reverse_lookup"`
+	SSLReverseLookup  bool  `json:"ssl_reverse_lookup"`
 	RunningAsService  bool  `json:"running_as_service"`
 }
 
@@ -51,17 +54,17 @@ type DeviceInfo struct {
 
 // ServerInfo represents detailed information about a server/hub
 type ServerInfo struct {
-	Name           string `json:"name"`
-	Version        string `json:"version"`
-	State          string `json:"state"`
-	Address        string `json:"address"`
-	Port           string `json:"port"`
-	ConnectedFor   string `json:"connected_for"` // e.g., "9265 sec"
-	MaxDevices     string `json:"max_devices"`
-	ConnectionID   string `json:"connection_id"`
-	Interface      string `json:"interface"`
-	SerialNumber   string `json:"serial_number"`
-	EasyFind       string `json:"easy_find"`
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	State        string `json:"state"`
+	Address      string `json:"address"`
+	Port         string `json:"port"`
+	ConnectedFor string `json:"connected_for"` // e.g., "9265 sec"
+	MaxDevices   string `json:"max_devices"`
+	ConnectionID string `json:"connection_id"`
+	Interface    string `json:"interface"`
+	SerialNumber string `json:"serial_number"`
+	EasyFind     string `json:"easy_find"`
 }
 
 // ReverseClient represents a reverse client connection
@@ -72,8 +75,10 @@ type ReverseClient struct {
 
 // License represents a VirtualHere license
 type License struct {
-	Key    string `json:"key"`
-	Status string `json:"status"`
+	Key          string `json:"key"`
+	Status       string `json:"status"`        // e.g. "valid", empty if not reported
+	ServerSerial string `json:"server_serial"` // Serial of the server the key is for
+	MaxDevices   int    `json:"max_devices"`   // Licensed device count, or UnlimitedDevices
 }
 
 // XMLClientState represents the root XML structure from GET CLIENT STATE
@@ -84,84 +89,95 @@ type XMLClientState struct {
 
 // XMLServer represents a server connection in the XML state
 type XMLServer struct {
-	XMLName    xml.Name    `xml:"server" json:"-"`
+	XMLName    xml.Name            `xml:"server" json:"-"`
 	Connection XMLServerConnection `xml:"connection" json:"connection"`
-	Devices    []XMLDevice `xml:"device" json:"devices"`
+	Devices    []XMLDevice         `xml:"device" json:"devices"`
 }
 
 // XMLServerConnection represents server connection details
 type XMLServerConnection struct {
-	ConnectionID        int       `xml:"connectionId,attr" json:"connection_id"`
-	Secure              bool      `xml:"secure,attr" json:"secure"`
-	ServerMajor         int       `xml:"serverMajor,attr" json:"server_major"`
-	ServerMinor         int       `xml:"serverMinor,attr" json:"server_minor"`
-	ServerRevision      int       `xml:"serverRevision,attr" json:"server_revision"`
-	RemoteAdmin         bool      `xml:"remoteAdmin,attr" json:"remote_admin"`
-	ServerName          string    `xml:"serverName,attr" json:"server_name"`
-	InterfaceName       string    `xml:"interfaceName,attr" json:"interface_name"`
-	Hostname            string    `xml:"hostname,attr" json:"hostname"`
-	ServerSerial        string    `xml:"serverSerial,attr" json:"server_serial"`
-	LicenseMaxDevices   int       `xml:"license_max_devices,attr" json:"license_max_devices"`
-	State               int       `xml:"state,attr" json:"state"`
-	ConnectedTime       time.Time `xml:"connectedTime,attr" json:"connected_time"`
-	Host                string    `xml:"host,attr" json:"host"`
-	Port                int       `xml:"port,attr" json:"port"`
-	Error               bool      `xml:"error,attr" json:"error"`
-	UUID                string    `xml:"uuid,attr" json:"uuid"`
-	TransportID         string    `xml:"transportId,attr" json:"transport_id"`
-	EasyFindEnabled     bool      `xml:"easyFindEnabled,attr" json:"easy_find_enabled"`
-	EasyFindAvailable   bool      `xml:"easyFindAvailable,attr" json:"easy_find_available"`
-	EasyFindID          string    `xml:"easyFindId,attr" json:"easy_find_id"`
-	EasyFindPin         string    `xml:"easyFindPin,attr" json:"easy_find_pin"`
-	EasyFindAuthorized  int       `xml:"easyFindAuthorized,attr" json:"easy_find_authorized"`
-	IP                  string    `xml:"ip,attr" json:"ip"`
+	ConnectionID       int             `xml:"connectionId,attr" json:"connection_id"`
+	Secure             bool            `xml:"secure,attr" json:"secure"`
+	ServerMajor        int             `xml:"serverMajor,attr" json:"server_major"`
+	ServerMinor        int             `xml:"serverMinor,attr" json:"server_minor"`
+	ServerRevision     int             `xml:"serverRevision,attr" json:"server_revision"`
+	RemoteAdmin        bool            `xml:"remoteAdmin,attr" json:"remote_admin"`
+	ServerName         string          `xml:"serverName,attr" json:"server_name"`
+	InterfaceName      string          `xml:"interfaceName,attr" json:"interface_name"`
+	Hostname           string          `xml:"hostname,attr" json:"hostname"`
+	ServerSerial       string          `xml:"serverSerial,attr" json:"server_serial"`
+	LicenseMaxDevices  int             `xml:"license_max_devices,attr" json:"license_max_devices"`
+	State              ConnectionState `xml:"state,attr" json:"state"`
+	ConnectedTime      time.Time       `xml:"connectedTime,attr" json:"connected_time"`
+	Host               string          `xml:"host,attr" json:"host"`
+	Port               int             `xml:"port,attr" json:"port"`
+	Error              bool            `xml:"error,attr" json:"error"`
+	UUID               string          `xml:"uuid,attr" json:"uuid"`
+	TransportID        string          `xml:"transportId,attr" json:"transport_id"`
+	EasyFindEnabled    bool            `xml:"easyFindEnabled,attr" json:"easy_find_enabled"`
+	EasyFindAvailable  bool            `xml:"easyFindAvailable,attr" json:"easy_find_available"`
+	EasyFindID         string          `xml:"easyFindId,attr" json:"easy_find_id"`
+	EasyFindPin        string          `xml:"easyFindPin,attr" json:"easy_find_pin"`
+	EasyFindAuthorized int             `xml:"easyFindAuthorized,attr" json:"easy_find_authorized"`
+	IP                 string          `xml:"ip,attr" json:"ip"`
 }
 
 // XMLDevice represents a device in the XML state
 type XMLDevice struct {
-	Vendor                         string `xml:"vendor,attr" json:"vendor"`
-	Product                        string `xml:"product,attr" json:"product"`
-	IDVendor                       int    `xml:"idVendor,attr" json:"id_vendor"`
-	IDProduct                      int    `xml:"idProduct,attr" json:"id_product"`
-	Address                        int    `xml:"address,attr" json:"address"`
-	ConnectionID                   int    `xml:"connectionId,attr" json:"connection_id"`
-	State                          int    `xml:"state,attr" json:"state"`
-	ServerSerial                   string `xml:"serverSerial,attr" json:"server_serial"`
-	ServerName                     string `xml:"serverName,attr" json:"server_name"`
-	ServerInterfaceName            string `xml:"serverInterfaceName,attr" json:"server_interface_name"`
-	DeviceSerial                   string `xml:"deviceSerial,attr" json:"device_serial"`
-	ConnectionUUID                 string `xml:"connectionUUID,attr" json:"connection_uuid"`
-	BoundConnectionUUID            string `xml:"boundConnectionUUID,attr" json:"bound_connection_uuid"`
-	BoundConnectionIP              string `xml:"boundConnectionIp,attr" json:"bound_connection_ip"`
-	BoundConnectionIP6             string `xml:"boundConnectionIp6,attr" json:"bound_connection_ip6"`
-	BoundClientHostname            string `xml:"boundClientHostname,attr" json:"bound_client_hostname"`
-	Nickname                       string `xml:"nickname,attr" json:"nickname"`
-	ClientID                       string `xml:"clientId,attr" json:"client_id"`
-	NumConfigurations              int    `xml:"numConfigurations,attr" json:"num_configurations"`
-	NumInterfacesInFirstConfiguration int `xml:"numInterfacesInFirstConfiguration,attr" json:"num_interfaces_in_first_configuration"`
-	FirstInterfaceClass            int    `xml:"firstInterfaceClass,attr" json:"first_interface_class"`
-	FirstInterfaceSubClass         int    `xml:"firstInterfaceSubClass,attr" json:"first_interface_sub_class"`
-	FirstInterfaceProtocol         int    `xml:"firstInterfaceProtocol,attr" json:"first_interface_protocol"`
-	HideClientInfo                 bool   `xml:"hideClientInfo,attr" json:"hide_client_info"`
-	BadSerial                      bool   `xml:"badSerial,attr" json:"bad_serial"`
-	ParentHubPort                  int    `xml:"parentHubPort,attr" json:"parent_hub_port"`
-	ParentHubAddress               int    `xml:"parentHubAddress,attr" json:"parent_hub_address"`
-	ParentHubContainerID           string `xml:"parentHubContainerID,attr" json:"parent_hub_container_id"`
-	ParentHubContainerIDPrefix     int    `xml:"parentHubContainerIDPrefix,attr" json:"parent_hub_container_id_prefix"`
-	ContainerID                    string `xml:"containerID,attr" json:"container_id"`
-	ContainerIDPrefix              int    `xml:"containerIDPrefix,attr" json:"container_id_prefix"`
-	NumPorts                       int    `xml:"numPorts,attr" json:"num_ports"`
-	AutoUse                        string `xml:"autoUse,attr" json:"auto_use"` // "not-set", "on", "off", etc.
+	Vendor                            string      `xml:"vendor,attr" json:"vendor"`
+	Product                           string      `xml:"product,attr" json:"product"`
+	IDVendor                          int         `xml:"idVendor,attr" json:"id_vendor"`
+	IDProduct                         int         `xml:"idProduct,attr" json:"id_product"`
+	Address                           int         `xml:"address,attr" json:"address"`
+	ConnectionID                      int         `xml:"connectionId,attr" json:"connection_id"`
+	State                             DeviceState `xml:"state,attr" json:"state"`
+	ServerSerial                      string      `xml:"serverSerial,attr" json:"server_serial"`
+	ServerName                        string      `xml:"serverName,attr" json:"server_name"`
+	ServerInterfaceName               string      `xml:"serverInterfaceName,attr" json:"server_interface_name"`
+	DeviceSerial                      string      `xml:"deviceSerial,attr" json:"device_serial"`
+	ConnectionUUID                    string      `xml:"connectionUUID,attr" json:"connection_uuid"`
+	BoundConnectionUUID               string      `xml:"boundConnectionUUID,attr" json:"bound_connection_uuid"`
+	BoundConnectionIP                 string      `xml:"boundConnectionIp,attr" json:"bound_connection_ip"`
+	BoundConnectionIP6                string      `xml:"boundConnectionIp6,attr" json:"bound_connection_ip6"`
+	BoundClientHostname               string      `xml:"boundClientHostname,attr" json:"bound_client_hostname"`
+	Nickname                          string      `xml:"nickname,attr" json:"nickname"`
+	ClientID                          string      `xml:"clientId,attr" json:"client_id"`
+	NumConfigurations                 int         `xml:"numConfigurations,attr" json:"num_configurations"`
+	NumInterfacesInFirstConfiguration int         `xml:"numInterfacesInFirstConfiguration,attr" json:"num_interfaces_in_first_configuration"`
+	FirstInterfaceClass               int         `xml:"firstInterfaceClass,attr" json:"first_interface_class"`
+	FirstInterfaceSubClass            int         `xml:"firstInterfaceSubClass,attr" json:"first_interface_sub_class"`
+	FirstInterfaceProtocol            int         `xml:"firstInterfaceProtocol,attr" json:"first_interface_protocol"`
+	HideClientInfo                    bool        `xml:"hideClientInfo,attr" json:"hide_client_info"`
+	BadSerial                         bool        `xml:"badSerial,attr" json:"bad_serial"`
+	ParentHubPort                     int         `xml:"parentHubPort,attr" json:"parent_hub_port"`
+	ParentHubAddress                  int         `xml:"parentHubAddress,attr" json:"parent_hub_address"`
+	ParentHubContainerID              string      `xml:"parentHubContainerID,attr" json:"parent_hub_container_id"`
+	ParentHubContainerIDPrefix        int         `xml:"parentHubContainerIDPrefix,attr" json:"parent_hub_container_id_prefix"`
+	ContainerID                       string      `xml:"containerID,attr" json:"container_id"`
+	ContainerIDPrefix                 int         `xml:"containerIDPrefix,attr" json:"container_id_prefix"`
+	NumPorts                          int         `xml:"numPorts,attr" json:"num_ports"`
+	AutoUse                           AutoUseMode `xml:"autoUse,attr" json:"auto_use"` // "not-set", "auto-use-device", etc.
 }
 
 // Common errors
+// Errors returned for FAILED/ERROR responses are *CommandError values, which
+// match ErrCommandFailed and, when classified, one of the more specific errors.
 var (
-	ErrCommandFailed    = errors.New("command failed")
-	ErrCommandTimeout   = errors.New("command timeout (>5 seconds)")
-	ErrInvalidAddress   = errors.New("invalid address")
-	ErrServerNotFound   = errors.New("server not found")
-	ErrDeviceNotFound   = errors.New("device not found")
-	ErrDeviceInUse      = errors.New("device already in use")
-	ErrBinaryNotFound   = errors.New("virtualhere binary not found")
-	ErrInvalidResponse  = errors.New("invalid response from client")
+	ErrCommandFailed   = errors.New("command failed")
+	ErrCommandTimeout  = errors.New("command timeout (>5 seconds)")
+	ErrInvalidAddress  = errors.New("invalid address")
+	ErrServerNotFound  = errors.New("server not found")
+	ErrDeviceNotFound  = errors.New("device not found")
+	ErrDeviceInUse     = errors.New("device already in use")
+	ErrBinaryNotFound  = errors.New("virtualhere binary not found")
+	ErrInvalidResponse = errors.New("invalid response from client")
+	ErrSocketNotFound  = errors.New("virtualhere socket not found")
+	ErrUnknownCommand  = errors.New("unknown command")
+	ErrInvalidArgument = errors.New("invalid command argument")
+	ErrStateMismatch   = errors.New("setting did not reach the requested state")
+	ErrInvalidLicense  = errors.New("invalid license key")
+	ErrUnsupported     = errors.New("command not supported by the client")
+	ErrInvalidSelector = errors.New("invalid device selector")
+	ErrNoMatch         = errors.New("no device matches selector")
+	ErrAmbiguousMatch  = errors.New("selector matches more than one device")
 )
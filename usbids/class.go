@@ -0,0 +1,190 @@
+// Package usbids decodes USB identifiers into human-readable names.
+//
+// Class triplets (class, subclass, protocol), such as the first interface
+// reported for every device in GET CLIENT STATE, are decoded into a Category
+// without any external data. Vendor and product names are resolved from an
+// optional usb.ids database (http://www.linux-usb.org/usb-ids.html), loaded
+// with Load, LoadFile or LoadSystem:
+//
+//	db, err := usbids.LoadSystem()
+//	if err != nil {
+//		db = nil // names fall back to "0403:6001"
+//	}
+//	label := db.Name(device.VendorID, device.ProductID)
+//	kind := usbids.Describe(device.InterfaceClass, device.InterfaceSubClass, device.InterfaceProtocol)
+package usbids
+
+import "fmt"
+
+// Category is the kind of device a USB class code stands for
+type Category int
+
+const (
+	CategoryUnknown             Category = iota // Unassigned class code
+	CategoryPerInterface                        // Class 0x00: defined by each interface
+	CategoryAudio                               // Class 0x01
+	CategoryCommunications                      // Class 0x02: CDC control, e.g. serial adapters and modems
+	CategoryHID                                 // Class 0x03: keyboards, mice, game controllers
+	CategoryPhysical                            // Class 0x05
+	CategoryImage                               // Class 0x06: still cameras and scanners
+	CategoryPrinter                             // Class 0x07
+	CategoryMassStorage                         // Class 0x08: flash drives, disks, card readers
+	CategoryHub                                 // Class 0x09
+	CategoryCDCData                             // Class 0x0a
+	CategorySmartCard                           // Class 0x0b
+	CategoryContentSecurity                     // Class 0x0d
+	CategoryVideo                               // Class 0x0e: webcams and capture devices
+	CategoryPersonalHealthcare                  // Class 0x0f
+	CategoryAudioVideo                          // Class 0x10
+	CategoryBillboard                           // Class 0x11
+	CategoryTypeCBridge                         // Class 0x12
+	CategoryDiagnostic                          // Class 0xdc
+	CategoryWireless                            // Class 0xe0: Bluetooth and wireless adapters
+	CategoryMiscellaneous                       // Class 0xef
+	CategoryApplicationSpecific                 // Class 0xfe: DFU, IrDA bridges, test and measurement
+	CategoryVendorSpecific                      // Class 0xff: defined by the vendor, e.g. FTDI serial adapters
+)
+
+// classCategories maps USB class codes to their category
+var classCategories = map[int]Category{
+	0x00: CategoryPerInterface,
+	0x01: CategoryAudio,
+	0x02: CategoryCommunications,
+	0x03: CategoryHID,
+	0x05: CategoryPhysical,
+	0x06: CategoryImage,
+	0x07: CategoryPrinter,
+	0x08: CategoryMassStorage,
+	0x09: CategoryHub,
+	0x0a: CategoryCDCData,
+	0x0b: CategorySmartCard,
+	0x0d: CategoryContentSecurity,
+	0x0e: CategoryVideo,
+	0x0f: CategoryPersonalHealthcare,
+	0x10: CategoryAudioVideo,
+	0x11: CategoryBillboard,
+	0x12: CategoryTypeCBridge,
+	0xdc: CategoryDiagnostic,
+	0xe0: CategoryWireless,
+	0xef: CategoryMiscellaneous,
+	0xfe: CategoryApplicationSpecific,
+	0xff: CategoryVendorSpecific,
+}
+
+var categoryNames = map[Category]string{
+	CategoryUnknown:             "unknown",
+	CategoryPerInterface:        "per-interface",
+	CategoryAudio:               "audio",
+	CategoryCommunications:      "communications",
+	CategoryHID:                 "HID",
+	CategoryPhysical:            "physical",
+	CategoryImage:               "image",
+	CategoryPrinter:             "printer",
+	CategoryMassStorage:         "mass storage",
+	CategoryHub:                 "hub",
+	CategoryCDCData:             "CDC data",
+	CategorySmartCard:           "smart card",
+	CategoryContentSecurity:     "content security",
+	CategoryVideo:               "video",
+	CategoryPersonalHealthcare:  "personal healthcare",
+	CategoryAudioVideo:          "audio/video",
+	CategoryBillboard:           "billboard",
+	CategoryTypeCBridge:         "USB-C bridge",
+	CategoryDiagnostic:          "diagnostic",
+	CategoryWireless:            "wireless",
+	CategoryMiscellaneous:       "miscellaneous",
+	CategoryApplicationSpecific: "application specific",
+	CategoryVendorSpecific:      "vendor specific",
+}
+
+// String returns the name of the category, e.g. "mass storage"
+func (c Category) String() string {
+	if name, ok := categoryNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("category(%d)", int(c))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (c Category) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Classify returns the category of a USB class code
+func Classify(class int) Category {
+	if category, ok := classCategories[class]; ok {
+		return category
+	}
+	return CategoryUnknown
+}
+
+// Describe returns a short description of a class triplet, naming well-known
+// subclasses and protocols where it can, e.g. "CDC serial", "HID keyboard" or
+// "mass storage (SCSI)"
+func Describe(class, subClass, protocol int) string {
+	category := Classify(class)
+
+	switch category {
+	case CategoryCommunications:
+		switch subClass {
+		case 0x02:
+			return "CDC serial"
+		case 0x06:
+			return "CDC Ethernet"
+		case 0x0d:
+			return "CDC network (NCM)"
+		case 0x0e:
+			return "CDC network (MBIM)"
+		}
+	case CategoryHID:
+		if subClass == 0x01 {
+			switch protocol {
+			case 0x01:
+				return "HID keyboard"
+			case 0x02:
+				return "HID mouse"
+			}
+		}
+	case CategoryMassStorage:
+		switch subClass {
+		case 0x06:
+			if protocol == 0x62 {
+				return "mass storage (UAS)"
+			}
+			return "mass storage (SCSI)"
+		case 0x02:
+			return "mass storage (ATAPI)"
+		case 0x04:
+			return "mass storage (floppy)"
+		}
+	case CategoryHub:
+		switch protocol {
+		case 0x01, 0x02:
+			return "hub (high speed)"
+		case 0x03:
+			return "hub (SuperSpeed)"
+		}
+	case CategoryVideo:
+		if subClass == 0x01 {
+			return "video control"
+		}
+		if subClass == 0x02 {
+			return "video streaming"
+		}
+	case CategoryWireless:
+		if subClass == 0x01 && protocol == 0x01 {
+			return "Bluetooth"
+		}
+	case CategoryApplicationSpecific:
+		switch subClass {
+		case 0x01:
+			return "firmware update (DFU)"
+		case 0x02:
+			return "IrDA bridge"
+		case 0x03:
+			return "test and measurement (USBTMC)"
+		}
+	}
+
+	return category.String()
+}
@@ -0,0 +1,163 @@
+package usbids
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SystemPaths are the locations LoadSystem looks for usb.ids, in order
+var SystemPaths = []string{
+	"/usr/share/hwdata/usb.ids",
+	"/usr/share/misc/usb.ids",
+	"/usr/share/usb.ids",
+	"/var/lib/usbutils/usb.ids",
+}
+
+// Database resolves vendor and product IDs to names. A nil *Database is
+// valid and resolves nothing, so callers can fall back to hexadecimal IDs
+// without checking whether a database was loaded.
+type Database struct {
+	vendors map[uint16]*vendor
+}
+
+// vendor is a vendor entry and its products
+type vendor struct {
+	name     string
+	products map[uint16]string
+}
+
+// Load parses a database in the usb.ids format. Only the vendor and product
+// section is used; the class, HID and language tables that follow it are ignored.
+func Load(r io.Reader) (*Database, error) {
+	db := &Database{vendors: make(map[uint16]*vendor)}
+	scanner := bufio.NewScanner(r)
+
+	var current *vendor
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "\t\t"):
+			// Interface of a product
+			continue
+		case strings.HasPrefix(line, "\t"):
+			if current == nil {
+				continue
+			}
+			id, name, ok := parseEntry(line[1:])
+			if !ok {
+				return nil, fmt.Errorf("usb.ids line %d: invalid product entry %q", lineNumber, line)
+			}
+			current.products[id] = name
+		default:
+			id, name, ok := parseEntry(line)
+			if !ok {
+				// Start of another table, e.g. "C 00  (Defined at Interface level)"
+				current = nil
+				continue
+			}
+			current = &vendor{name: name, products: make(map[uint16]string)}
+			db.vendors[id] = current
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usb.ids: %w", err)
+	}
+
+	return db, nil
+}
+
+// LoadFile parses the usb.ids database at path
+func LoadFile(path string) (*Database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// LoadSystem parses the first usb.ids found in SystemPaths. It returns an
+// error matching fs.ErrNotExist if none is installed.
+func LoadSystem() (*Database, error) {
+	for _, path := range SystemPaths {
+		db, err := LoadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return db, err
+	}
+	return nil, fmt.Errorf("usb.ids not found in %s: %w", strings.Join(SystemPaths, ", "), fs.ErrNotExist)
+}
+
+// parseEntry parses "xxxx  name" where xxxx is a hexadecimal ID
+func parseEntry(line string) (uint16, string, bool) {
+	if len(line) < 6 || line[4] != ' ' {
+		return 0, "", false
+	}
+	id, err := strconv.ParseUint(line[:4], 16, 16)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint16(id), strings.TrimSpace(line[5:]), true
+}
+
+// Vendor returns the name of the vendor, if known
+func (db *Database) Vendor(vendorID uint16) (string, bool) {
+	if db == nil {
+		return "", false
+	}
+	v, ok := db.vendors[vendorID]
+	if !ok {
+		return "", false
+	}
+	return v.name, true
+}
+
+// Product returns the name of the vendor's product, if known
+func (db *Database) Product(vendorID, productID uint16) (string, bool) {
+	if db == nil {
+		return "", false
+	}
+	v, ok := db.vendors[vendorID]
+	if !ok {
+		return "", false
+	}
+	name, ok := v.products[productID]
+	return name, ok
+}
+
+// Name returns "Vendor Product" for the IDs, or as much of it as is known.
+// Unknown IDs are formatted as "vvvv:pppp" in hexadecimal.
+func (db *Database) Name(vendorID, productID uint16) string {
+	vendorName, vendorOK := db.Vendor(vendorID)
+	productName, productOK := db.Product(vendorID, productID)
+
+	switch {
+	case vendorOK && productOK:
+		return vendorName + " " + productName
+	case vendorOK:
+		return fmt.Sprintf("%s %04x", vendorName, productID)
+	default:
+		return fmt.Sprintf("%04x:%04x", vendorID, productID)
+	}
+}
+
+// Len returns the number of vendors in the database
+func (db *Database) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.vendors)
+}
@@ -0,0 +1,168 @@
+package usbids
+
+import (
+	"strings"
+	"testing"
+)
+
+// excerpt is a trimmed usb.ids, keeping the layout of every kind of table
+const excerpt = `#
+#	List of USB ID's
+#
+# Syntax:
+# vendor  vendor_name
+#	device  device_name				<-- single tab
+#		interface  interface_name		<-- two tabs
+
+0403  Future Technology Devices International, Ltd
+	6001  FT232 Serial (UART) IC
+	6010  FT2232C/D/H Dual UART/FIFO IC
+		00  Interface A
+0483  STMicroelectronics
+	3748  ST-LINK/V2
+1d6b  Linux Foundation
+
+# List of known device classes, subclasses and protocols
+C 00  (Defined at Interface level)
+C 02  Communications
+	02  Abstract (modem)
+		01  AT-commands (v.25ter)
+C 03  Human Interface Device
+	01  Boot Interface Subclass
+		01  Keyboard
+
+# List of Audio Class Terminal Types
+AT 0100  USB Undefined
+	0101  USB Streaming
+
+# List of HID Descriptor Types
+HID 21  HID
+R 23  Physical
+
+# List of HID Descriptor Item Types
+HUT 01  Generic Desktop Controls
+	001  Pointer
+L 0409  English
+	01  US
+`
+
+func TestLoad(t *testing.T) {
+	db, err := Load(strings.NewReader(excerpt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The tables after the vendors add nothing, nor do interface entries
+	if n := db.Len(); n != 3 {
+		t.Errorf("Len() = %d, want 3", n)
+	}
+	tests := []struct {
+		vendor, product uint16
+		want            string
+		ok              bool
+	}{
+		{0x0403, 0x6001, "FT232 Serial (UART) IC", true},
+		{0x0403, 0x6010, "FT2232C/D/H Dual UART/FIFO IC", true},
+		{0x0483, 0x3748, "ST-LINK/V2", true},
+		{0x0403, 0x0000, "", false},
+		{0x0002, 0x0002, "", false},
+		{0x0100, 0x0101, "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := db.Product(tt.vendor, tt.product); got != tt.want || ok != tt.ok {
+			t.Errorf("Product(%04x, %04x) = %q, %v, want %q, %v", tt.vendor, tt.product, got, ok, tt.want, tt.ok)
+		}
+	}
+	if got, ok := db.Vendor(0x1d6b); got != "Linux Foundation" || !ok {
+		t.Errorf("Vendor(1d6b) = %q, %v", got, ok)
+	}
+}
+
+func TestLoadInvalidProduct(t *testing.T) {
+	tests := []string{
+		"0403  FTDI\n\t60  Short ID\n",
+		"0403  FTDI\n\tzzzz  Not hexadecimal\n",
+		"0403  FTDI\n\t6001\n",
+	}
+
+	for _, input := range tests {
+		_, err := Load(strings.NewReader(input))
+		if err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("Load(%q) returned %v, want an error on line 2", input, err)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	db, err := Load(strings.NewReader(excerpt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		db              *Database
+		vendor, product uint16
+		want            string
+	}{
+		{db, 0x0403, 0x6001, "Future Technology Devices International, Ltd FT232 Serial (UART) IC"},
+		{db, 0x0403, 0xbeef, "Future Technology Devices International, Ltd beef"},
+		{db, 0x1d6b, 0x0002, "Linux Foundation 0002"},
+		{db, 0x0bad, 0x6001, "0bad:6001"},
+		{nil, 0x0403, 0x6001, "0403:6001"},
+	}
+	for _, tt := range tests {
+		if got := tt.db.Name(tt.vendor, tt.product); got != tt.want {
+			t.Errorf("Name(%04x, %04x) = %q, want %q", tt.vendor, tt.product, got, tt.want)
+		}
+	}
+
+	var none *Database
+	if none.Len() != 0 {
+		t.Error("nil Database has vendors")
+	}
+	if _, ok := none.Vendor(0x0403); ok {
+		t.Error("nil Database resolved a vendor")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		class, subClass, protocol int
+		want                      string
+	}{
+		{0x02, 0x02, 0x01, "CDC serial"},
+		{0x02, 0x06, 0x00, "CDC Ethernet"},
+		{0x02, 0x0a, 0x00, "communications"},
+		{0x03, 0x01, 0x01, "HID keyboard"},
+		{0x03, 0x01, 0x02, "HID mouse"},
+		{0x03, 0x00, 0x00, "HID"},
+		{0x08, 0x06, 0x50, "mass storage (SCSI)"},
+		{0x08, 0x06, 0x62, "mass storage (UAS)"},
+		{0x08, 0x05, 0x00, "mass storage"},
+		{0x09, 0x00, 0x03, "hub (SuperSpeed)"},
+		{0x0e, 0x02, 0x00, "video streaming"},
+		{0xe0, 0x01, 0x01, "Bluetooth"},
+		{0xfe, 0x01, 0x02, "firmware update (DFU)"},
+		{0xff, 0xff, 0xff, "vendor specific"},
+		{0x00, 0x00, 0x00, "per-interface"},
+		{0x42, 0x00, 0x00, "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := Describe(tt.class, tt.subClass, tt.protocol); got != tt.want {
+			t.Errorf("Describe(%#02x, %#02x, %#02x) = %q, want %q", tt.class, tt.subClass, tt.protocol, got, tt.want)
+		}
+	}
+}
+
+func TestCategoryString(t *testing.T) {
+	if got := Classify(0x08).String(); got != "mass storage" {
+		t.Errorf("Classify(0x08) = %q", got)
+	}
+	if got := Category(99).String(); got != "category(99)" {
+		t.Errorf("Category(99) = %q", got)
+	}
+	if text, _ := CategoryTypeCBridge.MarshalText(); string(text) != "USB-C bridge" {
+		t.Errorf("MarshalText() = %q", text)
+	}
+}
@@ -0,0 +1,90 @@
+package virtualhere
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a VirtualHere client or server version such as 4.3.1. Versions
+// are comparable with Compare; the zero value means unknown.
+type Version struct {
+	Major    int
+	Minor    int
+	Revision int
+}
+
+// ParseVersion parses a version of the form "major[.minor[.revision]]",
+// optionally prefixed with "v"
+func ParseVersion(s string) (Version, error) {
+	text := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	parts := strings.Split(text, ".")
+	if text == "" || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Revision: numbers[2]}, nil
+}
+
+// String returns the version as "major.minor.revision", or "" if zero
+func (v Version) String() string {
+	if v.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Revision)
+}
+
+// IsZero reports whether the version is unknown
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is older than, equal to
+// or newer than other
+func (v Version) Compare(other Version) int {
+	if c := cmp.Compare(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	return cmp.Compare(v.Revision, other.Revision)
+}
+
+// AtLeast reports whether v is other or newer
+func (v Version) AtLeast(other Version) bool {
+	return v.Compare(other) >= 0
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (v *Version) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*v = Version{}
+		return nil
+	}
+	parsed, err := ParseVersion(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// Version returns the version of the server
+func (c *XMLServerConnection) Version() Version {
+	return Version{Major: c.ServerMajor, Minor: c.ServerMinor, Revision: c.ServerRevision}
+}
@@ -0,0 +1,84 @@
+package virtualhere
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Version
+		wantErr bool
+	}{
+		{"4.3.1", Version{4, 3, 1}, false},
+		{"v5.5.3", Version{5, 5, 3}, false},
+		{" 4.6 ", Version{4, 6, 0}, false},
+		{"5", Version{5, 0, 0}, false},
+		{"4.10.12", Version{4, 10, 12}, false},
+		{"", Version{}, true},
+		{"v", Version{}, true},
+		{"4.3.1.2", Version{}, true},
+		{"4..1", Version{}, true},
+		{"4.3.x", Version{}, true},
+		{"4.-3.1", Version{}, true},
+		{"4.3.1-beta", Version{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, %v, want %v (error %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b Version
+		want int
+	}{
+		{Version{4, 3, 1}, Version{4, 3, 1}, 0},
+		{Version{4, 3, 1}, Version{4, 3, 2}, -1},
+		{Version{4, 10, 0}, Version{4, 9, 9}, 1},
+		{Version{5, 0, 0}, Version{4, 99, 99}, 1},
+		{Version{}, Version{0, 0, 1}, -1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%v.Compare(%v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := tt.b.Compare(tt.a); got != -tt.want {
+			t.Errorf("%v.Compare(%v) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+		if got := tt.a.AtLeast(tt.b); got != (tt.want >= 0) {
+			t.Errorf("%v.AtLeast(%v) = %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestVersionText(t *testing.T) {
+	if s := (Version{}).String(); s != "" {
+		t.Errorf("zero Version String() = %q, want empty", s)
+	}
+
+	data, err := json.Marshal(struct{ V Version }{Version{4, 3, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"V":"4.3.1"}` {
+		t.Errorf("Marshal = %s", data)
+	}
+
+	var v struct{ V Version }
+	if err := json.Unmarshal([]byte(`{"V":"v5.5"}`), &v); err != nil || v.V != (Version{5, 5, 0}) {
+		t.Errorf("Unmarshal = %v, %v", v.V, err)
+	}
+	if err := json.Unmarshal([]byte(`{"V":""}`), &v); err != nil || !v.V.IsZero() {
+		t.Errorf("Unmarshal of empty = %v, %v", v.V, err)
+	}
+	if err := json.Unmarshal([]byte(`{"V":"four"}`), &v); err == nil {
+		t.Error("Unmarshal of an invalid version succeeded")
+	}
+}
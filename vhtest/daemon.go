@@ -0,0 +1,698 @@
+package vhtest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tryanks/virtualhere-go"
+)
+
+// Settings are the client-wide switches reported at the end of LIST
+type Settings struct {
+	AutoFind         bool
+	AutoUseAll       bool
+	ReverseLookup    bool
+	SSLReverse       bool
+	RunningAsService bool
+}
+
+// daemonState is the emulated client state; it is guarded by Server.mu
+type daemonState struct {
+	hubs        []Hub
+	settings    Settings
+	manualHubs  []string
+	reverse     map[string][]string
+	licenses    []string
+	connectedAt time.Time
+}
+
+func newDaemonState() daemonState {
+	return daemonState{
+		settings:    Settings{AutoFind: true},
+		reverse:     make(map[string][]string),
+		connectedAt: time.Now(),
+	}
+}
+
+// AddHub adds a hub, replacing any hub with the same address
+func (s *Server) AddHub(hub Hub) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hub.Devices = append([]Device(nil), hub.Devices...)
+	for i := range s.state.hubs {
+		if s.state.hubs[i].Address == hub.Address {
+			s.state.hubs[i] = hub
+			return
+		}
+	}
+	s.state.hubs = append(s.state.hubs, hub)
+}
+
+// RemoveHub removes the hub with the given address, as if it disconnected
+func (s *Server) RemoveHub(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.state.hubs {
+		if s.state.hubs[i].Address == address {
+			s.state.hubs = append(s.state.hubs[:i], s.state.hubs[i+1:]...)
+			return
+		}
+	}
+}
+
+// AddDevice attaches a device to the hub with the given address
+func (s *Server) AddDevice(hubAddress string, device Device) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.state.hubs {
+		if s.state.hubs[i].Address == hubAddress {
+			s.state.hubs[i].Devices = append(s.state.hubs[i].Devices, device)
+			return nil
+		}
+	}
+	return fmt.Errorf("vhtest: no hub with address %q", hubAddress)
+}
+
+// RemoveDevice detaches the device with the given address, e.g. "raspberrypi.114"
+func (s *Server) RemoveDevice(address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hub, index := s.state.findDevice(address)
+	if hub == nil {
+		return fmt.Errorf("vhtest: no device with address %q", address)
+	}
+	hub.Devices = append(hub.Devices[:index], hub.Devices[index+1:]...)
+	return nil
+}
+
+// UpdateDevice calls update with the device at address so a test can change
+// its state, e.g. mark it as in use by another client
+func (s *Server) UpdateDevice(address string, update func(*Device)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hub, index := s.state.findDevice(address)
+	if hub == nil {
+		return fmt.Errorf("vhtest: no device with address %q", address)
+	}
+	update(&hub.Devices[index])
+	return nil
+}
+
+// Hubs returns a copy of the current hubs and their devices
+func (s *Server) Hubs() []Hub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hubs := make([]Hub, len(s.state.hubs))
+	for i, hub := range s.state.hubs {
+		hub.Devices = append([]Device(nil), hub.Devices...)
+		hubs[i] = hub
+	}
+	return hubs
+}
+
+// SetSettings replaces the client-wide switches
+func (s *Server) SetSettings(settings Settings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.settings = settings
+}
+
+// Settings returns the client-wide switches, including changes made by commands
+func (s *Server) Settings() Settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.settings
+}
+
+// ManualHubs returns the hubs added with MANUAL HUB ADD
+func (s *Server) ManualHubs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.state.manualHubs...)
+}
+
+// SetLicenses sets the raw lines returned by LIST LICENSES
+func (s *Server) SetLicenses(lines ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.licenses = append([]string(nil), lines...)
+}
+
+// hostname returns the prefix of the hub's device addresses
+func (h *Hub) hostname() string {
+	if h.Hostname != "" {
+		return h.Hostname
+	}
+	if i := strings.LastIndex(h.Address, ":"); i >= 0 {
+		return h.Address[:i]
+	}
+	return h.Address
+}
+
+// autoUse returns the auto-use mode reported for device: its own rule, or
+// the hub rule if it has none
+func (h *Hub) autoUse(device *Device) virtualhere.AutoUseMode {
+	switch {
+	case device.AutoUse != "" && device.AutoUse != virtualhere.AutoUseNotSet:
+		return device.AutoUse
+	case h.AutoUse:
+		return virtualhere.AutoUseModeHub
+	default:
+		return virtualhere.AutoUseNotSet
+	}
+}
+
+// deviceAddress returns the full address of a device on the hub
+func (h *Hub) deviceAddress(d *Device) string {
+	return fmt.Sprintf("%s.%d", h.hostname(), d.Address)
+}
+
+// findHub returns the hub whose name, address or hostname matches key
+func (st *daemonState) findHub(key string) *Hub {
+	for i := range st.hubs {
+		hub := &st.hubs[i]
+		if hub.Name == key || hub.Address == key || hub.hostname() == key {
+			return hub
+		}
+	}
+	return nil
+}
+
+// findDevice returns the hub holding the device with the given full address
+// and the device's index in it
+func (st *daemonState) findDevice(address string) (*Hub, int) {
+	for i := range st.hubs {
+		hub := &st.hubs[i]
+		for j := range hub.Devices {
+			if hub.deviceAddress(&hub.Devices[j]) == address {
+				return hub, j
+			}
+		}
+	}
+	return nil, -1
+}
+
+// execute emulates the daemon's handling of a single command
+func (st *daemonState) execute(command string) string {
+	parts := strings.Split(command, ",")
+	verb, args := parts[0], parts[1:]
+
+	switch verb {
+	case "LIST":
+		return st.list()
+	case "GET CLIENT STATE":
+		return st.clientStateXML()
+	case "USE":
+		return st.use(args)
+	case "STOP USING":
+		return st.stopUsing(args)
+	case "STOP USING ALL", "STOP USING ALL LOCAL":
+		return st.stopUsingAll(verb, args)
+	case "DEVICE INFO":
+		return st.deviceInfo(args)
+	case "SERVER INFO":
+		return st.serverInfo(args)
+	case "DEVICE RENAME":
+		return st.deviceRename(args)
+	case "SERVER RENAME":
+		return st.serverRename(args)
+	case "AUTO USE ALL":
+		st.settings.AutoUseAll = !st.settings.AutoUseAll
+		return "OK"
+	case "AUTO USE HUB":
+		return st.autoUseHub(args)
+	case "AUTO USE PORT":
+		return st.toggleAutoUse(args, virtualhere.AutoUseModePort)
+	case "AUTO USE DEVICE":
+		return st.toggleAutoUse(args, virtualhere.AutoUseModeDevice)
+	case "AUTO USE DEVICE PORT":
+		return st.toggleAutoUse(args, virtualhere.AutoUseModeDevicePort)
+	case "AUTO USE CLEAR ALL":
+		return st.autoUseClearAll()
+	case "MANUAL HUB ADD":
+		return st.manualHubAdd(args)
+	case "MANUAL HUB REMOVE":
+		return st.manualHubRemove(args)
+	case "MANUAL HUB REMOVE ALL":
+		st.manualHubs = nil
+		return "OK"
+	case "MANUAL HUB LIST":
+		return strings.Join(st.manualHubs, "\n")
+	case "ADD REVERSE", "REMOVE REVERSE":
+		return st.updateReverse(verb, args)
+	case "LIST REVERSE":
+		if len(args) != 1 {
+			return "ERROR: invalid arguments"
+		}
+		return strings.Join(st.reverse[args[0]], "\n")
+	case "LIST LICENSES":
+		return strings.Join(st.licenses, "\n")
+	case "LICENSE SERVER":
+		if len(args) == 0 {
+			return "ERROR: invalid arguments"
+		}
+		return "OK"
+	case "CLEAR LOG", "EXIT":
+		return "OK"
+	case "CUSTOM EVENT":
+		if _, index := st.findDevice(argument(args, 0)); index < 0 {
+			return "ERROR: device not found"
+		}
+		return "OK"
+	case "AUTOFIND":
+		st.settings.AutoFind = !st.settings.AutoFind
+		return "OK"
+	case "REVERSE":
+		st.settings.ReverseLookup = !st.settings.ReverseLookup
+		return "OK"
+	case "SSLREVERSE":
+		st.settings.SSLReverse = !st.settings.SSLReverse
+		return "OK"
+	case "HELP":
+		return helpText
+	}
+
+	return "ERROR: unknown command"
+}
+
+// argument returns args[i] or "" if there are fewer arguments
+func argument(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+// list renders the LIST output
+func (st *daemonState) list() string {
+	var b strings.Builder
+	b.WriteString("VirtualHere IPC, below are the available devices:\n")
+	b.WriteString("(Value in brackets = address, * = Auto-Use)\n\n")
+
+	for i := range st.hubs {
+		hub := &st.hubs[i]
+		fmt.Fprintf(&b, "%s (%s)\n", hub.Name, hub.Address)
+		for j := range hub.Devices {
+			device := &hub.Devices[j]
+			fmt.Fprintf(&b, "   --> %s (%s)", deviceLabel(device), hub.deviceAddress(device))
+			if st.settings.AutoUseAll || hub.autoUse(device).Enabled() {
+				b.WriteString(" *")
+			}
+			switch device.InUseBy {
+			case "":
+			case ClientHostname:
+				b.WriteString(" (In-use by you)")
+			default:
+				fmt.Fprintf(&b, " (In-use by %s)", device.InUseBy)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "Auto-Find currently %s\n", onOff(st.settings.AutoFind))
+	fmt.Fprintf(&b, "Auto-Use All currently %s\n", onOff(st.settings.AutoUseAll))
+	fmt.Fprintf(&b, "Reverse Lookup currently %s\n", onOff(st.settings.ReverseLookup))
+	fmt.Fprintf(&b, "Reverse SSL Lookup currently %s\n", onOff(st.settings.SSLReverse))
+	if st.settings.RunningAsService {
+		b.WriteString("VirtualHere Client is running as a service\n")
+	} else {
+		b.WriteString("VirtualHere Client not running as a service\n")
+	}
+
+	return b.String()
+}
+
+// deviceLabel returns how LIST names a device: its nickname followed by the
+// product name in square brackets, or just the product name
+func deviceLabel(d *Device) string {
+	if d.Nickname != "" {
+		return fmt.Sprintf("%s [%s]", d.Nickname, d.Product)
+	}
+	return d.Product
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// clientStateXML renders the GET CLIENT STATE document
+func (st *daemonState) clientStateXML() string {
+	state := virtualhere.XMLClientState{}
+
+	for i := range st.hubs {
+		hub := &st.hubs[i]
+		connectionID := i + 1
+		version, _ := virtualhere.ParseVersion(hub.Version)
+		host, port := splitHostPort(hub.Address)
+
+		server := virtualhere.XMLServer{
+			Connection: virtualhere.XMLServerConnection{
+				ConnectionID:   connectionID,
+				ServerMajor:    version.Major,
+				ServerMinor:    version.Minor,
+				ServerRevision: version.Revision,
+				ServerName:     hub.Name,
+				Hostname:       hub.hostname(),
+				ServerSerial:   hub.Serial,
+				State:          virtualhere.ConnectionConnected,
+				ConnectedTime:  st.connectedAt,
+				Host:           host,
+				Port:           port,
+				IP:             host,
+			},
+		}
+
+		for j := range hub.Devices {
+			device := &hub.Devices[j]
+			xmlDevice := virtualhere.XMLDevice{
+				Vendor:                 device.Vendor,
+				Product:                device.Product,
+				IDVendor:               device.VendorID,
+				IDProduct:              device.ProductID,
+				Address:                device.Address,
+				ConnectionID:           connectionID,
+				State:                  virtualhere.DeviceAvailable,
+				ServerSerial:           hub.Serial,
+				ServerName:             hub.Name,
+				DeviceSerial:           device.Serial,
+				Nickname:               device.Nickname,
+				FirstInterfaceClass:    device.Class,
+				FirstInterfaceSubClass: device.SubClass,
+				FirstInterfaceProtocol: device.Protocol,
+				ParentHubPort:          device.ConnectionPort,
+				AutoUse:                hub.autoUse(device),
+			}
+			if device.InUseBy != "" {
+				xmlDevice.State = virtualhere.DeviceInUseByOther
+				if device.InUseBy == ClientHostname {
+					xmlDevice.State = virtualhere.DeviceInUseByMe
+				}
+				xmlDevice.BoundClientHostname = device.InUseBy
+				xmlDevice.BoundConnectionIP = device.HolderIP
+			}
+			server.Devices = append(server.Devices, xmlDevice)
+		}
+
+		state.Servers = append(state.Servers, server)
+	}
+
+	out, err := xml.Marshal(state)
+	if err != nil {
+		return "ERROR: " + err.Error()
+	}
+	return string(out)
+}
+
+// splitHostPort splits "host:port", defaulting the port to 7575
+func splitHostPort(address string) (string, int) {
+	i := strings.LastIndex(address, ":")
+	if i < 0 {
+		return address, 7575
+	}
+	port, err := strconv.Atoi(address[i+1:])
+	if err != nil {
+		return address, 7575
+	}
+	return address[:i], port
+}
+
+func (st *daemonState) use(args []string) string {
+	hub, index := st.findDevice(argument(args, 0))
+	if hub == nil {
+		return "ERROR: device not found"
+	}
+
+	device := &hub.Devices[index]
+	switch device.InUseBy {
+	case "":
+	case ClientHostname:
+		return "OK"
+	default:
+		return "ERROR: device in use by " + device.InUseBy
+	}
+
+	// The password runs to the end of the line, so it may contain commas
+	password := ""
+	if len(args) > 1 {
+		password = strings.Join(args[1:], ",")
+	}
+	if device.Password != "" && device.Password != password {
+		return "FAILED"
+	}
+
+	device.InUseBy = ClientHostname
+	device.HolderIP = "127.0.0.1"
+	return "OK"
+}
+
+func (st *daemonState) stopUsing(args []string) string {
+	hub, index := st.findDevice(argument(args, 0))
+	if hub == nil {
+		return "ERROR: device not found"
+	}
+
+	device := &hub.Devices[index]
+	if device.InUseBy != ClientHostname {
+		return "FAILED"
+	}
+
+	device.InUseBy = ""
+	device.HolderIP = ""
+	return "OK"
+}
+
+func (st *daemonState) stopUsingAll(verb string, args []string) string {
+	hubs := st.hubs
+	if verb == "STOP USING ALL" && len(args) > 0 {
+		hub := st.findHub(args[0])
+		if hub == nil {
+			return "ERROR: server not found"
+		}
+		i := hubIndex(st.hubs, hub)
+		hubs = st.hubs[i : i+1]
+	}
+
+	for i := range hubs {
+		for j := range hubs[i].Devices {
+			device := &hubs[i].Devices[j]
+			if device.InUseBy == ClientHostname {
+				device.InUseBy = ""
+				device.HolderIP = ""
+			}
+		}
+	}
+	return "OK"
+}
+
+// hubIndex returns the index of hub within hubs
+func hubIndex(hubs []Hub, hub *Hub) int {
+	for i := range hubs {
+		if &hubs[i] == hub {
+			return i
+		}
+	}
+	return -1
+}
+
+func (st *daemonState) deviceInfo(args []string) string {
+	hub, index := st.findDevice(argument(args, 0))
+	if hub == nil {
+		return "ERROR: device not found"
+	}
+
+	device := &hub.Devices[index]
+	inUseBy := "NO ONE"
+	if device.InUseBy != "" {
+		inUseBy = device.InUseBy
+	}
+
+	return fmt.Sprintf("ADDRESS: %s\nVENDOR: %s\nVENDOR ID: 0x%04x\nPRODUCT: %s\nPRODUCT ID: 0x%04x\nSERIAL: %s\nIN USE BY: %s",
+		hub.deviceAddress(device), device.Vendor, device.VendorID, device.Product, device.ProductID, device.Serial, inUseBy)
+}
+
+func (st *daemonState) serverInfo(args []string) string {
+	hub := st.findHub(argument(args, 0))
+	if hub == nil {
+		return "ERROR: server not found"
+	}
+
+	host, port := splitHostPort(hub.Address)
+	connectedFor := int(time.Since(st.connectedAt).Seconds())
+
+	return fmt.Sprintf("NAME: %s\nVERSION: %s\nSTATE: Logged in\nADDRESS: %s (%s)\nPORT: %d\nCONNECTED FOR: %d sec\nMAX DEVICES: unlimited\nCONNECTION ID: %d\nINTERFACE:\nSERIAL NUMBER: %s\nEASYFIND: not enabled",
+		hub.Name, hub.Version, host, host, port, connectedFor, hubIndex(st.hubs, hub)+1, hub.Serial)
+}
+
+func (st *daemonState) deviceRename(args []string) string {
+	if len(args) != 2 {
+		return "ERROR: invalid arguments"
+	}
+
+	hub, index := st.findDevice(args[0])
+	if hub == nil {
+		return "ERROR: device not found"
+	}
+	hub.Devices[index].Nickname = args[1]
+	return "OK"
+}
+
+func (st *daemonState) serverRename(args []string) string {
+	if len(args) != 2 {
+		return "ERROR: invalid arguments"
+	}
+
+	hub := st.findHub(args[0])
+	if hub == nil {
+		return "ERROR: server not found"
+	}
+	hub.Name = args[1]
+	return "OK"
+}
+
+// autoUseHub toggles the hub auto-use rule. Rules of individual devices are
+// left alone and keep taking precedence.
+func (st *daemonState) autoUseHub(args []string) string {
+	hub := st.findHub(argument(args, 0))
+	if hub == nil {
+		return "ERROR: server not found"
+	}
+
+	// Devices scripted as auto-use-hub stand for the hub rule
+	enabled := hub.AutoUse
+	for j := range hub.Devices {
+		if hub.Devices[j].AutoUse == virtualhere.AutoUseModeHub {
+			enabled = true
+			hub.Devices[j].AutoUse = ""
+		}
+	}
+
+	hub.AutoUse = !enabled
+	return "OK"
+}
+
+// toggleAutoUse switches a single device between mode and not-set
+func (st *daemonState) toggleAutoUse(args []string, mode virtualhere.AutoUseMode) string {
+	hub, index := st.findDevice(argument(args, 0))
+	if hub == nil {
+		return "ERROR: device not found"
+	}
+
+	device := &hub.Devices[index]
+	if device.AutoUse == mode {
+		device.AutoUse = ""
+	} else {
+		device.AutoUse = mode
+	}
+	return "OK"
+}
+
+func (st *daemonState) autoUseClearAll() string {
+	st.settings.AutoUseAll = false
+	for i := range st.hubs {
+		st.hubs[i].AutoUse = false
+		for j := range st.hubs[i].Devices {
+			st.hubs[i].Devices[j].AutoUse = ""
+		}
+	}
+	return "OK"
+}
+
+func (st *daemonState) manualHubAdd(args []string) string {
+	address := argument(args, 0)
+	if address == "" {
+		return "ERROR: invalid address"
+	}
+
+	for _, hub := range st.manualHubs {
+		if hub == address {
+			return "OK"
+		}
+	}
+	st.manualHubs = append(st.manualHubs, address)
+	return "OK"
+}
+
+func (st *daemonState) manualHubRemove(args []string) string {
+	address := argument(args, 0)
+	for i, hub := range st.manualHubs {
+		if hub == address {
+			st.manualHubs = append(st.manualHubs[:i], st.manualHubs[i+1:]...)
+			return "OK"
+		}
+	}
+	return "FAILED"
+}
+
+func (st *daemonState) updateReverse(verb string, args []string) string {
+	if len(args) != 2 {
+		return "ERROR: invalid arguments"
+	}
+
+	serial, address := args[0], args[1]
+	clients := st.reverse[serial]
+	for i, client := range clients {
+		if client == address {
+			if verb == "REMOVE REVERSE" {
+				st.reverse[serial] = append(clients[:i], clients[i+1:]...)
+			}
+			return "OK"
+		}
+	}
+
+	if verb == "REMOVE REVERSE" {
+		return "FAILED"
+	}
+	st.reverse[serial] = append(clients, address)
+	return "OK"
+}
+
+// helpText mirrors the command summary printed by the HELP command
+const helpText = `VirtualHere Client IPC, below are the available commands (press Q to quit):
+
+ - LIST - list the devices of all servers
+ - GET CLIENT STATE - detailed state as XML
+ - USE,<address>[,password] - use a device
+ - STOP USING,<address>
+ - STOP USING ALL[,<server address>]
+ - STOP USING ALL LOCAL
+ - DEVICE INFO,<address>
+ - SERVER INFO,<server name>
+ - DEVICE RENAME,<address>,<nickname>
+ - SERVER RENAME,<hub address:port>,<name>
+ - AUTO USE ALL - toggle auto-use of all devices
+ - AUTO USE HUB,<server name>
+ - AUTO USE PORT,<address>
+ - AUTO USE DEVICE,<address>
+ - AUTO USE DEVICE PORT,<address>
+ - AUTO USE CLEAR ALL
+ - MANUAL HUB ADD,<host or IP address>[:port] | <EasyFind address>
+ - MANUAL HUB REMOVE,<host or IP address>[:port] | <EasyFind address>
+ - MANUAL HUB REMOVE ALL
+ - MANUAL HUB LIST
+ - ADD REVERSE,<server serial>,<client address>
+ - REMOVE REVERSE,<server serial>,<client address>
+ - LIST REVERSE,<server serial>
+ - LIST LICENSES
+ - LICENSE SERVER,<license key>
+ - CLEAR LOG
+ - CUSTOM EVENT,<address>,<event>
+ * AUTOFIND - toggle Auto-Find
+ * REVERSE - toggle Reverse Lookup
+ * SSLREVERSE - toggle Reverse SSL Lookup
+ - HELP
+ - EXIT
+
+Devices are addressed as <server>.<address>, as shown by LIST.`
@@ -0,0 +1,272 @@
+// Package vhtest provides an in-process fake VirtualHere client daemon for tests.
+//
+// A Server listens on a request and a response Unix socket in a temporary
+// directory, just like vhclient does in /tmp, and answers commands from a
+// scripted set of hubs and devices:
+//
+//	srv, err := vhtest.NewServer()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer srv.Close()
+//
+//	srv.AddHub(vhtest.Hub{Name: "Raspberry Hub", Address: "raspberrypi:7575"})
+//	srv.AddDevice("raspberrypi:7575", vhtest.Device{Address: 114, Product: "Ultra USB 3.0"})
+//
+//	client, err := srv.Client()
+//	state, err := client.List()
+package vhtest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tryanks/virtualhere-go"
+)
+
+// ClientHostname is the hostname the fake daemon reports for itself, e.g. as
+// the holder of devices used through it
+const ClientHostname = "vhtest-client"
+
+// responseWait bounds how long a request waits for its response connection
+const responseWait = 5 * time.Second
+
+// Server is a fake VirtualHere client daemon. Its methods are safe for
+// concurrent use, including while commands are being served.
+type Server struct {
+	Dir          string // Temporary directory holding both sockets
+	RequestPath  string // Socket commands are written to
+	ResponsePath string // Socket responses are read from
+
+	requestListener  net.Listener
+	responseListener net.Listener
+	responseConns    chan net.Conn
+	done             chan struct{}
+	wg               sync.WaitGroup
+	closeOnce        sync.Once
+
+	mu       sync.Mutex
+	state    daemonState
+	scripted map[string]string
+	commands []string
+}
+
+// Hub describes a scripted VirtualHere server (hub)
+type Hub struct {
+	Name     string // e.g. "Raspberry Hub"
+	Address  string // e.g. "raspberrypi:7575"
+	Hostname string // Prefix of device addresses; defaults to the host part of Address
+	Serial   string // Server serial number
+	Version  string // Server version, e.g. "4.6.4"
+	AutoUse  bool   // Hub auto-use rule (AUTO USE HUB); devices without a rule of their own report auto-use-hub
+	Devices  []Device
+}
+
+// Device describes a scripted USB device attached to a Hub
+type Device struct {
+	Address        int                     // Numeric device address, e.g. 114 for "raspberrypi.114"
+	Vendor         string                  // e.g. "FTDI"
+	Product        string                  // e.g. "FT232R USB UART"
+	VendorID       int                     // e.g. 0x0403
+	ProductID      int                     // e.g. 0x6001
+	Serial         string                  // Device serial number
+	Nickname       string                  // Custom nickname, empty if none
+	Class          int                     // First interface class
+	SubClass       int                     // First interface subclass
+	Protocol       int                     // First interface protocol
+	Password       string                  // Password required by USE, empty if none
+	InUseBy        string                  // Holder hostname; empty if free, ClientHostname if used by this client
+	AutoUse        virtualhere.AutoUseMode // Auto-use rule of the device; empty means none, so the hub rule applies
+	HolderIP       string                  // IP address of the holder, if in use
+	ConnectionPort int                     // Port the device is attached to on the hub
+}
+
+// NewServer starts a fake daemon listening on fresh sockets in a temporary
+// directory. Call Close to stop it and remove the directory.
+func NewServer() (*Server, error) {
+	dir, err := os.MkdirTemp("", "vhtest")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	s := &Server{
+		Dir:           dir,
+		RequestPath:   filepath.Join(dir, "vhclient"),
+		ResponsePath:  filepath.Join(dir, "vhclient_response"),
+		responseConns: make(chan net.Conn),
+		done:          make(chan struct{}),
+		state:         newDaemonState(),
+		scripted:      make(map[string]string),
+	}
+
+	s.responseListener, err = net.Listen("unix", s.ResponsePath)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to listen on response socket: %w", err)
+	}
+
+	s.requestListener, err = net.Listen("unix", s.RequestPath)
+	if err != nil {
+		s.responseListener.Close()
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to listen on request socket: %w", err)
+	}
+
+	s.wg.Add(2)
+	go s.acceptResponses()
+	go s.acceptRequests()
+
+	return s, nil
+}
+
+// Client returns a virtualhere.Client connected to this server.
+// Additional options are applied after the socket paths.
+func (s *Server) Client(opts ...virtualhere.ClientOption) (*virtualhere.Client, error) {
+	opts = append([]virtualhere.ClientOption{virtualhere.WithSocketPaths(s.RequestPath, s.ResponsePath)}, opts...)
+	return virtualhere.NewPipeClient(opts...)
+}
+
+// Close stops the server and removes its socket directory
+func (s *Server) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = errors.Join(s.requestListener.Close(), s.responseListener.Close())
+		s.wg.Wait()
+		err = errors.Join(err, os.RemoveAll(s.Dir))
+	})
+	return err
+}
+
+// SetResponse scripts the raw response for a command. key is either a full
+// command ("USE,raspberrypi.114") or just its verb ("USE"); a full command
+// takes precedence. Use it to inject failures such as "FAILED" or
+// "ERROR: device in use".
+func (s *Server) SetResponse(key string, response string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripted[key] = response
+}
+
+// ClearResponse removes a response scripted with SetResponse
+func (s *Server) ClearResponse(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.scripted, key)
+}
+
+// Commands returns every command received so far, in order
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// acceptResponses hands every response connection to the next request
+func (s *Server) acceptResponses() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.responseListener.Accept()
+		if err != nil {
+			return
+		}
+
+		select {
+		case s.responseConns <- conn:
+		case <-s.done:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// acceptRequests serves request connections one at a time, like the daemon
+func (s *Server) acceptRequests() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.requestListener.Accept()
+		if err != nil {
+			return
+		}
+		s.serve(conn)
+	}
+}
+
+// serve reads one command from conn and writes the answer to the oldest
+// waiting response connection that is still open
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(responseWait))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && line == "" {
+		return
+	}
+	command := strings.TrimRight(line, "\r\n")
+
+	responseConn := s.nextResponseConn()
+	if responseConn == nil {
+		return
+	}
+	defer responseConn.Close()
+
+	response := s.handle(command)
+	_, _ = responseConn.Write([]byte(response + "\n"))
+}
+
+// nextResponseConn returns the oldest response connection whose client is
+// still connected, or nil if none arrives in time. A client that gave up
+// before sending its command has closed its response connection, which must
+// not receive the answer meant for the next caller.
+func (s *Server) nextResponseConn() net.Conn {
+	timeout := time.After(responseWait)
+	for {
+		select {
+		case conn := <-s.responseConns:
+			if peerConnected(conn) {
+				return conn
+			}
+			conn.Close()
+		case <-timeout:
+			return nil
+		case <-s.done:
+			return nil
+		}
+	}
+}
+
+// peerConnected reports whether the client end of a response connection is
+// still open. Clients never write to it, so a read that times out means it is.
+func peerConnected(conn net.Conn) bool {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	_, err := conn.Read(make([]byte, 1))
+	_ = conn.SetReadDeadline(time.Time{})
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// handle records command and returns the scripted or emulated response
+func (s *Server) handle(command string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commands = append(s.commands, command)
+
+	verb, _, _ := strings.Cut(command, ",")
+	if response, ok := s.scripted[command]; ok {
+		return response
+	}
+	if response, ok := s.scripted[verb]; ok {
+		return response
+	}
+
+	return s.state.execute(command)
+}
@@ -0,0 +1,274 @@
+//go:build !windows
+// +build !windows
+
+package vhtest
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tryanks/virtualhere-go"
+)
+
+// newTestServer starts a server with one hub holding three devices
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	srv.AddHub(Hub{Name: "Raspberry Hub", Address: "raspberrypi:7575", Devices: []Device{
+		{Address: 114, Product: "Ultra USB 3.0"},
+		{Address: 115, Product: "FT232R USB UART", Password: "a,b"},
+		{Address: 116, Product: "Keyboard", InUseBy: "bob-pc"},
+	}})
+	srv.AddHub(Hub{Name: "Lab Hub", Address: "lab:7575", Devices: []Device{
+		{Address: 1, Product: "JTAG", InUseBy: ClientHostname},
+	}})
+	return srv
+}
+
+// send writes command to the server as the client daemon would receive it
+// and returns the raw response
+func send(t *testing.T, srv *Server, command string) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	transport := &virtualhere.UnixSocketTransport{RequestPath: srv.RequestPath, ResponsePath: srv.ResponsePath}
+	response, err := transport.Send(ctx, command)
+	if err != nil {
+		t.Fatalf("%s: %v", command, err)
+	}
+	return strings.TrimRight(response, "\n")
+}
+
+// inUseBy returns the holder of the device at address
+func inUseBy(t *testing.T, srv *Server, address string) string {
+	t.Helper()
+	var holder string
+	if err := srv.UpdateDevice(address, func(d *Device) { holder = d.InUseBy }); err != nil {
+		t.Fatal(err)
+	}
+	return holder
+}
+
+func TestStaleResponseConnection(t *testing.T) {
+	srv := newTestServer(t)
+
+	// A client that connects the response socket and gives up before
+	// writing its command must not receive the next caller's answer
+	stale, err := net.Dial("unix", srv.ResponsePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.Close()
+
+	if got := send(t, srv, "DEVICE INFO,raspberrypi.114"); !strings.HasPrefix(got, "ADDRESS: raspberrypi.114\n") {
+		t.Errorf("DEVICE INFO returned %q", got)
+	}
+	if got := send(t, srv, "LIST"); !strings.Contains(got, "Raspberry Hub (raspberrypi:7575)") {
+		t.Errorf("LIST returned %q", got)
+	}
+}
+
+func TestUse(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		command string
+		want    string
+		holder  string // Holder of the device afterwards
+	}{
+		{"USE,raspberrypi.114", "OK", ClientHostname},
+		{"USE,raspberrypi.114", "OK", ClientHostname},
+		{"USE,raspberrypi.115", "FAILED", ""},
+		{"USE,raspberrypi.115,a", "FAILED", ""},
+		{"USE,raspberrypi.115,a,b", "OK", ClientHostname},
+		{"USE,raspberrypi.116", "ERROR: device in use by bob-pc", "bob-pc"},
+		{"USE,raspberrypi.999", "ERROR: device not found", ""},
+	}
+
+	for _, tt := range tests {
+		if got := send(t, srv, tt.command); got != tt.want {
+			t.Errorf("%s returned %q, want %q", tt.command, got, tt.want)
+		}
+		address := strings.Split(tt.command, ",")[1]
+		if address == "raspberrypi.999" {
+			continue
+		}
+		if holder := inUseBy(t, srv, address); holder != tt.holder {
+			t.Errorf("after %s, %s is in use by %q, want %q", tt.command, address, holder, tt.holder)
+		}
+	}
+
+	list := send(t, srv, "LIST")
+	for _, line := range []string{
+		"--> Ultra USB 3.0 (raspberrypi.114) (In-use by you)",
+		"--> Keyboard (raspberrypi.116) (In-use by bob-pc)",
+	} {
+		if !strings.Contains(list, line) {
+			t.Errorf("LIST does not contain %q:\n%s", line, list)
+		}
+	}
+	if info := send(t, srv, "DEVICE INFO,raspberrypi.114"); !strings.HasSuffix(info, "IN USE BY: "+ClientHostname) {
+		t.Errorf("DEVICE INFO returned %q", info)
+	}
+}
+
+func TestStopUsing(t *testing.T) {
+	srv := newTestServer(t)
+	send(t, srv, "USE,raspberrypi.114")
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"STOP USING,raspberrypi.116", "FAILED"},
+		{"STOP USING,raspberrypi.999", "ERROR: device not found"},
+		{"STOP USING ALL,nowhere", "ERROR: server not found"},
+		{"STOP USING ALL,lab", "OK"},
+	}
+	for _, tt := range tests {
+		if got := send(t, srv, tt.command); got != tt.want {
+			t.Errorf("%s returned %q, want %q", tt.command, got, tt.want)
+		}
+	}
+
+	// STOP USING ALL limited to a hub leaves the devices of others alone
+	if holder := inUseBy(t, srv, "lab.1"); holder != "" {
+		t.Errorf("lab.1 is in use by %q after STOP USING ALL,lab", holder)
+	}
+	if holder := inUseBy(t, srv, "raspberrypi.114"); holder != ClientHostname {
+		t.Errorf("raspberrypi.114 is in use by %q after STOP USING ALL,lab", holder)
+	}
+	if holder := inUseBy(t, srv, "raspberrypi.116"); holder != "bob-pc" {
+		t.Errorf("raspberrypi.116 is in use by %q, want bob-pc", holder)
+	}
+
+	if got := send(t, srv, "STOP USING,raspberrypi.114"); got != "OK" {
+		t.Errorf("STOP USING returned %q", got)
+	}
+	if holder := inUseBy(t, srv, "raspberrypi.114"); holder != "" {
+		t.Errorf("raspberrypi.114 is in use by %q after STOP USING", holder)
+	}
+	if got := send(t, srv, "STOP USING,raspberrypi.114"); got != "FAILED" {
+		t.Errorf("second STOP USING returned %q, want FAILED", got)
+	}
+}
+
+// autoUseModes returns the auto-use mode GET CLIENT STATE reports per device
+func autoUseModes(t *testing.T, srv *Server) map[int]virtualhere.AutoUseMode {
+	t.Helper()
+	client, err := srv.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := client.GetClientState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modes := make(map[int]virtualhere.AutoUseMode)
+	for _, device := range state.Servers[0].Devices {
+		modes[device.Address] = device.AutoUse
+	}
+	return modes
+}
+
+func TestAutoUse(t *testing.T) {
+	srv := newTestServer(t)
+
+	send(t, srv, "AUTO USE DEVICE,raspberrypi.114")
+	send(t, srv, "AUTO USE PORT,raspberrypi.115")
+	want := map[int]virtualhere.AutoUseMode{
+		114: virtualhere.AutoUseModeDevice,
+		115: virtualhere.AutoUseModePort,
+		116: virtualhere.AutoUseNotSet,
+	}
+	if got := autoUseModes(t, srv); !reflect.DeepEqual(got, want) {
+		t.Errorf("after AUTO USE DEVICE and PORT, modes are %v, want %v", got, want)
+	}
+
+	// The hub rule applies to devices without a rule of their own only
+	send(t, srv, "AUTO USE HUB,Raspberry Hub")
+	want[116] = virtualhere.AutoUseModeHub
+	if got := autoUseModes(t, srv); !reflect.DeepEqual(got, want) {
+		t.Errorf("after AUTO USE HUB, modes are %v, want %v", got, want)
+	}
+	if !srv.Hubs()[0].AutoUse {
+		t.Error("AUTO USE HUB did not enable the hub rule")
+	}
+	if list := send(t, srv, "LIST"); !strings.Contains(list, "--> Keyboard (raspberrypi.116) * (In-use by bob-pc)") {
+		t.Errorf("LIST does not mark the hub rule:\n%s", list)
+	}
+
+	// Toggling a device rule off makes the hub rule apply to it again
+	send(t, srv, "AUTO USE DEVICE,raspberrypi.114")
+	want[114] = virtualhere.AutoUseModeHub
+	if got := autoUseModes(t, srv); !reflect.DeepEqual(got, want) {
+		t.Errorf("after toggling AUTO USE DEVICE off, modes are %v, want %v", got, want)
+	}
+
+	send(t, srv, "AUTO USE HUB,raspberrypi")
+	want[114], want[116] = virtualhere.AutoUseNotSet, virtualhere.AutoUseNotSet
+	if got := autoUseModes(t, srv); !reflect.DeepEqual(got, want) {
+		t.Errorf("after toggling AUTO USE HUB off, modes are %v, want %v", got, want)
+	}
+
+	send(t, srv, "AUTO USE CLEAR ALL")
+	want[115] = virtualhere.AutoUseNotSet
+	if got := autoUseModes(t, srv); !reflect.DeepEqual(got, want) {
+		t.Errorf("after AUTO USE CLEAR ALL, modes are %v, want %v", got, want)
+	}
+
+	for _, want := range []bool{true, false} {
+		send(t, srv, "AUTO USE ALL")
+		if got := srv.Settings().AutoUseAll; got != want {
+			t.Errorf("AUTO USE ALL set auto-use all to %v, want %v", got, want)
+		}
+	}
+
+	if got := send(t, srv, "AUTO USE HUB,nowhere"); got != "ERROR: server not found" {
+		t.Errorf("AUTO USE HUB of an unknown hub returned %q", got)
+	}
+}
+
+func TestScriptedResponses(t *testing.T) {
+	srv := newTestServer(t)
+
+	srv.SetResponse("USE", "ERROR: device in use")
+	srv.SetResponse("USE,raspberrypi.115", "FAILED")
+
+	if got := send(t, srv, "USE,raspberrypi.114"); got != "ERROR: device in use" {
+		t.Errorf("USE with a scripted verb returned %q", got)
+	}
+	if got := send(t, srv, "USE,raspberrypi.115"); got != "FAILED" {
+		t.Errorf("USE with a scripted command returned %q", got)
+	}
+	if holder := inUseBy(t, srv, "raspberrypi.114"); holder != "" {
+		t.Errorf("scripted USE changed the state: in use by %q", holder)
+	}
+
+	srv.ClearResponse("USE")
+	if got := send(t, srv, "USE,raspberrypi.114"); got != "OK" {
+		t.Errorf("USE after ClearResponse returned %q", got)
+	}
+	if got := send(t, srv, "USE,raspberrypi.115"); got != "FAILED" {
+		t.Errorf("USE with a scripted command returned %q after clearing the verb", got)
+	}
+	if got := send(t, srv, "FROBNICATE"); got != "ERROR: unknown command" {
+		t.Errorf("unknown command returned %q", got)
+	}
+
+	want := []string{"USE,raspberrypi.114", "USE,raspberrypi.115", "USE,raspberrypi.114", "USE,raspberrypi.115", "FROBNICATE"}
+	if got := srv.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Commands() = %q, want %q", got, want)
+	}
+}
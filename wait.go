@@ -0,0 +1,133 @@
+package virtualhere
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// waitPolicy spaces the polls of the WaitFor helpers; only its delays are used
+var waitPolicy = RetryPolicy{
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     1.5,
+	Jitter:         0.2,
+}
+
+// WaitError is returned by the WaitFor helpers when ctx expires before the
+// condition held. It records what was last observed.
+type WaitError struct {
+	Condition string // What was waited for, e.g. "device raspberrypi.114 to be in-use-by-me"
+	Last      string // Last observed state, e.g. "in-use-by-other (bob-pc)"
+	Err       error  // Context error
+}
+
+func (e *WaitError) Error() string {
+	return fmt.Sprintf("timed out waiting for %s (last observed: %s): %v", e.Condition, e.Last, e.Err)
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// WaitForHub polls GET CLIENT STATE until the server whose name, hostname or
+// address is key is connected, and returns it
+func (c *Client) WaitForHub(ctx context.Context, key string) (HubRef, error) {
+	var hub HubRef
+	err := c.waitFor(ctx, fmt.Sprintf("hub %q to connect", key), func(state *XMLClientState) (bool, string) {
+		server := state.findServer(key)
+		if server == nil {
+			return false, "not listed"
+		}
+		if !server.Connection.State.Connected() {
+			return false, server.Connection.State.String()
+		}
+		hub = server.hubRef()
+		return true, ""
+	})
+	return hub, err
+}
+
+// WaitForDevice polls GET CLIENT STATE until the device at address is listed,
+// and returns it
+func (c *Client) WaitForDevice(ctx context.Context, address DeviceAddress) (RemoteDevice, error) {
+	return c.waitForDevice(ctx, address, "to appear", func(RemoteDevice) bool { return true })
+}
+
+// WaitForDeviceState polls GET CLIENT STATE until the device at address is in
+// state, e.g. DeviceInUseByMe after Use, and returns it
+func (c *Client) WaitForDeviceState(ctx context.Context, address DeviceAddress, state DeviceState) (RemoteDevice, error) {
+	return c.waitForDevice(ctx, address, "to be "+state.String(), func(device RemoteDevice) bool {
+		return device.State == state
+	})
+}
+
+// WaitUntilReleased polls GET CLIENT STATE until the device at address is no
+// longer in use by any client, and returns it
+func (c *Client) WaitUntilReleased(ctx context.Context, address DeviceAddress) (RemoteDevice, error) {
+	return c.waitForDevice(ctx, address, "to be released", func(device RemoteDevice) bool {
+		return device.State.Available()
+	})
+}
+
+// waitForDevice waits until the device at address is listed and satisfies done
+func (c *Client) waitForDevice(ctx context.Context, address DeviceAddress, want string, done func(RemoteDevice) bool) (RemoteDevice, error) {
+	if address.IsZero() {
+		return RemoteDevice{}, fmt.Errorf("%w: empty device address", ErrInvalidAddress)
+	}
+
+	var found RemoteDevice
+	err := c.waitFor(ctx, "device "+address.String()+" "+want, func(state *XMLClientState) (bool, string) {
+		for _, device := range state.Devices() {
+			if device.Address != address {
+				continue
+			}
+			if !done(device) {
+				return false, describeDevice(device)
+			}
+			found = device
+			return true, ""
+		}
+		return false, "not listed"
+	})
+	return found, err
+}
+
+// describeDevice summarises the state of a device for a WaitError
+func describeDevice(device RemoteDevice) string {
+	if device.State.InUseByOther() && device.HolderHostname != "" {
+		return fmt.Sprintf("%s (%s)", device.State, device.HolderHostname)
+	}
+	return device.State.String()
+}
+
+// waitFor polls GET CLIENT STATE with backoff until check reports done. check
+// otherwise describes what it observed, which ends up in the WaitError once
+// ctx expires. Polls are queued at PriorityLow unless ctx carries another
+// priority. Polls failing with a transient IPC error, such as the daemon not
+// listening yet, are retried until ctx expires; any other error, such as a
+// CommandError or ErrUnsupported, is returned at once.
+func (c *Client) waitFor(ctx context.Context, condition string, check func(*XMLClientState) (bool, string)) error {
+	ctx = withDefaultPriority(ctx, PriorityLow)
+
+	last := "nothing, no poll completed"
+	for attempt := 1; ; attempt++ {
+		state, err := c.GetClientStateContext(ctx)
+		if err == nil {
+			done, observed := check(state)
+			if done {
+				return nil
+			}
+			last = observed
+		} else if ctx.Err() == nil {
+			if !isRetryable(err) {
+				return fmt.Errorf("waiting for %s: %w", condition, err)
+			}
+			last = "poll failed: " + err.Error()
+		}
+
+		if err := sleepContext(ctx, waitPolicy.backoff(attempt)); err != nil {
+			return &WaitError{Condition: condition, Last: last, Err: err}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+//go:build !windows
+// +build !windows
+
+package virtualhere_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Tryanks/virtualhere-go"
+	"github.com/Tryanks/virtualhere-go/vhtest"
+)
+
+var pi114 = virtualhere.DeviceAddress{Hub: "raspberrypi", Address: 114}
+
+// newWaitServer returns a daemon with one device, in use by bob-pc
+func newWaitServer(t *testing.T) (*vhtest.Server, *virtualhere.Client) {
+	t.Helper()
+	srv := newServer(t)
+	srv.AddHub(vhtest.Hub{Name: "Raspberry Hub", Address: "raspberrypi:7575", Devices: []vhtest.Device{
+		{Address: 114, Product: "FT232R USB UART", InUseBy: "bob-pc"},
+	}})
+	client, err := srv.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+// later runs change once the first poll has been answered
+func later(t *testing.T, srv *vhtest.Server, change func()) {
+	t.Helper()
+	go func() {
+		for countCommands(srv, "GET CLIENT STATE") == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		change()
+	}()
+}
+
+func TestWaitForHub(t *testing.T) {
+	srv := newServer(t)
+	client, err := srv.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	later(t, srv, func() { srv.AddHub(vhtest.Hub{Name: "Raspberry Hub", Address: "raspberrypi:7575"}) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	hub, err := client.WaitForHub(ctx, "Raspberry Hub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hub.Name != "Raspberry Hub" || hub.Address.String() != "raspberrypi:7575" {
+		t.Errorf("WaitForHub() = %+v", hub)
+	}
+	if n := countCommands(srv, "GET CLIENT STATE"); n < 2 {
+		t.Errorf("polled %d time(s), want at least 2", n)
+	}
+}
+
+func TestWaitForDeviceState(t *testing.T) {
+	srv, client := newWaitServer(t)
+	later(t, srv, func() {
+		srv.UpdateDevice("raspberrypi.114", func(d *vhtest.Device) { d.InUseBy = vhtest.ClientHostname })
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	device, err := client.WaitForDeviceState(ctx, pi114, virtualhere.DeviceInUseByMe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Address != pi114 || !device.State.InUseByMe() {
+		t.Errorf("WaitForDeviceState() = %+v", device)
+	}
+}
+
+func TestWaitUntilReleased(t *testing.T) {
+	srv, client := newWaitServer(t)
+	later(t, srv, func() {
+		srv.UpdateDevice("raspberrypi.114", func(d *vhtest.Device) { d.InUseBy = "" })
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	device, err := client.WaitUntilReleased(ctx, pi114)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !device.State.Available() {
+		t.Errorf("WaitUntilReleased() returned a device in state %s", device.State)
+	}
+}
+
+func TestWaitError(t *testing.T) {
+	_, client := newWaitServer(t)
+
+	tests := []struct {
+		name      string
+		wait      func(ctx context.Context) error
+		condition string
+		last      string
+	}{
+		{"hub", func(ctx context.Context) error {
+			_, err := client.WaitForHub(ctx, "lab")
+			return err
+		}, `hub "lab" to connect`, "not listed"},
+		{"device", func(ctx context.Context) error {
+			_, err := client.WaitForDevice(ctx, virtualhere.DeviceAddress{Hub: "raspberrypi", Address: 115})
+			return err
+		}, "device raspberrypi.115 to appear", "not listed"},
+		{"device state", func(ctx context.Context) error {
+			_, err := client.WaitForDeviceState(ctx, pi114, virtualhere.DeviceInUseByMe)
+			return err
+		}, "device raspberrypi.114 to be in-use-by-me", "in-use-by-other (bob-pc)"},
+		{"release", func(ctx context.Context) error {
+			_, err := client.WaitUntilReleased(ctx, pi114)
+			return err
+		}, "device raspberrypi.114 to be released", "in-use-by-other (bob-pc)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+			defer cancel()
+
+			err := tt.wait(ctx)
+			var waitErr *virtualhere.WaitError
+			if !errors.As(err, &waitErr) {
+				t.Fatalf("got %v, want a *WaitError", err)
+			}
+			if waitErr.Condition != tt.condition || waitErr.Last != tt.last {
+				t.Errorf("WaitError waits for %q, last observed %q; want %q, %q", waitErr.Condition, waitErr.Last, tt.condition, tt.last)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("WaitError %v does not wrap the context error", err)
+			}
+		})
+	}
+
+	if _, err := client.WaitForDevice(context.Background(), virtualhere.DeviceAddress{}); !errors.Is(err, virtualhere.ErrInvalidAddress) {
+		t.Errorf("WaitForDevice with an empty address returned %v", err)
+	}
+}
+
+func TestWaitPermanentError(t *testing.T) {
+	srv, client := newWaitServer(t)
+	srv.SetResponse("GET CLIENT STATE", "ERROR: unknown command")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.WaitUntilReleased(ctx, pi114)
+
+	var cmdErr *virtualhere.CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("got %v, want a *CommandError", err)
+	}
+	var waitErr *virtualhere.WaitError
+	if errors.As(err, &waitErr) || ctx.Err() != nil {
+		t.Errorf("a permanent error was retried until the deadline: %v", err)
+	}
+	if n := countCommands(srv, "GET CLIENT STATE"); n != 1 {
+		t.Errorf("polled %d times, want 1", n)
+	}
+}
+
+func TestWaitRetriesTransientErrors(t *testing.T) {
+	srv, _ := newWaitServer(t)
+	srv.UpdateDevice("raspberrypi.114", func(d *vhtest.Device) { d.InUseBy = "" })
+
+	// The daemon refuses the first two polls, as while it starts up
+	daemon := &virtualhere.UnixSocketTransport{RequestPath: srv.RequestPath, ResponsePath: srv.ResponsePath}
+	var refused atomic.Int32
+	transport := virtualhere.TransportFunc(func(ctx context.Context, command string) (string, error) {
+		if refused.Add(1) <= 2 {
+			return "", syscall.ECONNREFUSED
+		}
+		return daemon.Send(ctx, command)
+	})
+	client, err := virtualhere.NewClientWithTransport(transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.WaitUntilReleased(ctx, pi114); err != nil {
+		t.Fatal(err)
+	}
+	if n := refused.Load(); n != 3 {
+		t.Errorf("polled %d times, want 3", n)
+	}
+}
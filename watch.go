@@ -0,0 +1,247 @@
+package virtualhere
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch polls the client state by default
+const DefaultWatchInterval = 5 * time.Second
+
+// EventType identifies what changed between two client state snapshots
+type EventType int
+
+const (
+	EventError           EventType = iota // Polling failed; see Event.Err
+	HubConnected                          // A server appeared
+	HubDisconnected                       // A server disappeared
+	DeviceArrived                         // A device appeared
+	DeviceRemoved                         // A device disappeared
+	DeviceClaimedByMe                     // This client started using a device
+	DeviceClaimedByOther                  // Another client started using a device, or it changed hands
+	DeviceReleased                        // A device in use became available
+	NicknameChanged                       // A device's nickname changed
+	AutoUseChanged                        // A device's auto-use mode changed
+)
+
+var eventTypeNames = map[EventType]string{
+	EventError:           "error",
+	HubConnected:         "hub-connected",
+	HubDisconnected:      "hub-disconnected",
+	DeviceArrived:        "device-arrived",
+	DeviceRemoved:        "device-removed",
+	DeviceClaimedByMe:    "device-claimed-by-me",
+	DeviceClaimedByOther: "device-claimed-by-other",
+	DeviceReleased:       "device-released",
+	NicknameChanged:      "nickname-changed",
+	AutoUseChanged:       "auto-use-changed",
+}
+
+// String returns the name of the event type, e.g. "device-arrived"
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("event(%d)", int(t))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// Event is a change observed by Watch
+type Event struct {
+	Type     EventType    `json:"type"`
+	Time     time.Time    `json:"time"`     // When the change was observed
+	Hub      HubRef       `json:"hub"`      // Server of the hub or device
+	Device   RemoteDevice `json:"device"`   // Device after the change, or before its removal; zero for hub events
+	Previous RemoteDevice `json:"previous"` // Device before the change, for claim, release, nickname and auto-use events
+	Err      error        `json:"-"`        // Polling error for EventError
+}
+
+// WatchOptions configures Watch
+type WatchOptions struct {
+	Interval time.Duration // Time between polls (0 = DefaultWatchInterval)
+
+	// Coalesce is a settle window: when a poll shows changes, Watch waits this
+	// long and polls again before emitting, so that bursts of changes are
+	// reported together and changes undone within the window are not reported
+	// at all. 0 emits changes as soon as they are seen.
+	Coalesce time.Duration
+
+	EmitInitial bool // Report the hubs and devices present at start as connected and arrived
+	Buffer      int  // Capacity of the event channel
+}
+
+// Watch polls GET CLIENT STATE and sends an event for every change between
+// successive snapshots until ctx is done, then closes the channel. Polls are
+// queued at PriorityLow unless ctx carries another priority. Polling errors are
+// sent as EventError events and polling continues. The initial snapshot is
+// taken before Watch returns, and its error is returned directly.
+func (c *Client) Watch(ctx context.Context, opts WatchOptions) (<-chan Event, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchInterval
+	}
+	ctx = withDefaultPriority(ctx, PriorityLow)
+
+	prev, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event, opts.Buffer)
+	go func() {
+		defer close(events)
+
+		if opts.EmitInitial && !emit(ctx, events, diffSnapshots(&snapshot{}, prev, time.Now())) {
+			return
+		}
+
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			cur, err := c.snapshot(ctx)
+			if err == nil && opts.Coalesce > 0 && len(diffSnapshots(prev, cur, time.Time{})) > 0 {
+				if sleepContext(ctx, opts.Coalesce) != nil {
+					return
+				}
+				cur, err = c.snapshot(ctx)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if !emit(ctx, events, []Event{{Type: EventError, Time: time.Now(), Err: err}}) {
+					return
+				}
+				continue
+			}
+
+			if !emit(ctx, events, diffSnapshots(prev, cur, time.Now())) {
+				return
+			}
+			prev = cur
+		}
+	}()
+
+	return events, nil
+}
+
+// emit sends events in order, reporting false if ctx was done first
+func emit(ctx context.Context, ch chan<- Event, events []Event) bool {
+	for _, event := range events {
+		select {
+		case ch <- event:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot is the client state reduced to what Watch compares
+type snapshot struct {
+	hubs    map[string]HubRef
+	devices map[string]RemoteDevice
+}
+
+// snapshot fetches the current client state
+func (c *Client) snapshot(ctx context.Context) (*snapshot, error) {
+	state, err := c.GetClientStateContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &snapshot{hubs: make(map[string]HubRef), devices: make(map[string]RemoteDevice)}
+	for i := range state.Servers {
+		hub := state.Servers[i].hubRef()
+		snap.hubs[hubKey(hub)] = hub
+	}
+	for _, device := range state.Devices() {
+		snap.devices[device.Address.String()] = device
+	}
+	return snap, nil
+}
+
+// hubKey identifies a hub across snapshots
+func hubKey(hub HubRef) string {
+	switch {
+	case hub.Serial != "":
+		return hub.Serial
+	case !hub.Address.IsZero():
+		return hub.Address.String()
+	default:
+		return hub.Name
+	}
+}
+
+// diffSnapshots returns the events leading from prev to cur: hubs connecting,
+// then device changes, then hubs disconnecting, each in key order
+func diffSnapshots(prev, cur *snapshot, now time.Time) []Event {
+	var events []Event
+
+	for _, key := range slices.Sorted(maps.Keys(cur.hubs)) {
+		if _, ok := prev.hubs[key]; !ok {
+			events = append(events, Event{Type: HubConnected, Time: now, Hub: cur.hubs[key]})
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(prev.devices)) {
+		if _, ok := cur.devices[key]; !ok {
+			device := prev.devices[key]
+			events = append(events, Event{Type: DeviceRemoved, Time: now, Hub: device.Hub, Device: device})
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(cur.devices)) {
+		device := cur.devices[key]
+		before, ok := prev.devices[key]
+		if !ok {
+			events = append(events, Event{Type: DeviceArrived, Time: now, Hub: device.Hub, Device: device})
+			continue
+		}
+		for _, t := range deviceChanges(before, device) {
+			events = append(events, Event{Type: t, Time: now, Hub: device.Hub, Device: device, Previous: before})
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(prev.hubs)) {
+		if _, ok := cur.hubs[key]; !ok {
+			events = append(events, Event{Type: HubDisconnected, Time: now, Hub: prev.hubs[key]})
+		}
+	}
+
+	return events
+}
+
+// deviceChanges returns the event types for the changes of a device
+func deviceChanges(before, after RemoteDevice) []EventType {
+	var types []EventType
+
+	switch {
+	case after.State.InUseByMe() && !before.State.InUseByMe():
+		types = append(types, DeviceClaimedByMe)
+	case after.State.InUseByOther() && (!before.State.InUseByOther() || after.HolderHostname != before.HolderHostname):
+		types = append(types, DeviceClaimedByOther)
+	case after.State.Available() && before.State.InUse():
+		types = append(types, DeviceReleased)
+	}
+	if after.Nickname != before.Nickname {
+		types = append(types, NicknameChanged)
+	}
+	if after.AutoUse.String() != before.AutoUse.String() {
+		types = append(types, AutoUseChanged)
+	}
+
+	return types
+}
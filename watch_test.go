@@ -0,0 +1,287 @@
+package virtualhere
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	watchPi  = HubRef{Name: "Raspberry Hub", Hostname: "raspberrypi", Serial: "b827eb1a2b3c"}
+	watchLab = HubRef{Name: "Lab Hub", Hostname: "lab", Serial: "e4b5d2c1a0f3"}
+)
+
+// watchDevice returns device address of hub in state
+func watchDevice(hub HubRef, address int, state DeviceState) RemoteDevice {
+	return RemoteDevice{Address: DeviceAddress{Hub: hub.Hostname, Address: address}, State: state, AutoUse: AutoUseNotSet, Hub: hub}
+}
+
+// newSnapshot returns a snapshot of hubs and devices keyed as Watch keys them
+func newSnapshot(hubs []HubRef, devices ...RemoteDevice) *snapshot {
+	snap := &snapshot{hubs: make(map[string]HubRef), devices: make(map[string]RemoteDevice)}
+	for _, hub := range hubs {
+		snap.hubs[hubKey(hub)] = hub
+	}
+	for _, device := range devices {
+		snap.devices[device.Address.String()] = device
+	}
+	return snap
+}
+
+func TestDeviceChanges(t *testing.T) {
+	available := watchDevice(watchPi, 114, DeviceAvailable)
+	mine := watchDevice(watchPi, 114, DeviceInUseByMe)
+	alices := watchDevice(watchPi, 114, DeviceInUseByOther)
+	alices.HolderHostname = "alice-laptop"
+	bobs := alices
+	bobs.HolderHostname = "bob-pc"
+	renamed := mine
+	renamed.Nickname = "jtag-1"
+	autoUsed := available
+	autoUsed.AutoUse = AutoUseModeDevice
+
+	tests := []struct {
+		name          string
+		before, after RemoteDevice
+		want          []EventType
+	}{
+		{"unchanged", available, available, nil},
+		{"claimed by me", available, mine, []EventType{DeviceClaimedByMe}},
+		{"claimed by other", available, alices, []EventType{DeviceClaimedByOther}},
+		{"taken from me", mine, alices, []EventType{DeviceClaimedByOther}},
+		{"taken from other", alices, mine, []EventType{DeviceClaimedByMe}},
+		{"holder changed", alices, bobs, []EventType{DeviceClaimedByOther}},
+		{"same holder", alices, alices, nil},
+		{"released by me", mine, available, []EventType{DeviceReleased}},
+		{"released by other", bobs, available, []EventType{DeviceReleased}},
+		{"state became known", watchDevice(watchPi, 114, DeviceStateUnknown), available, nil},
+		{"nickname changed", mine, renamed, []EventType{NicknameChanged}},
+		{"auto-use changed", available, autoUsed, []EventType{AutoUseChanged}},
+		{"several changes", autoUsed, renamed, []EventType{DeviceClaimedByMe, NicknameChanged, AutoUseChanged}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deviceChanges(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deviceChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// eventSummary is the part of an Event compared by the watch tests
+type eventSummary struct {
+	Type     EventType
+	Hub      string // Name of Event.Hub
+	Device   string // Event.Device address, empty for hub events
+	Previous string // Event.Previous address, empty if zero
+}
+
+func summarize(events []Event) []eventSummary {
+	summaries := make([]eventSummary, 0, len(events))
+	for _, event := range events {
+		summary := eventSummary{Type: event.Type, Hub: event.Hub.Name}
+		if !event.Device.Address.IsZero() {
+			summary.Device = event.Device.Address.String()
+		}
+		if !event.Previous.Address.IsZero() {
+			summary.Previous = event.Previous.Address.String()
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	now := time.Now()
+	pi114 := watchDevice(watchPi, 114, DeviceAvailable)
+	pi115 := watchDevice(watchPi, 115, DeviceAvailable)
+	lab1 := watchDevice(watchLab, 1, DeviceInUseByMe)
+	pi114Mine := watchDevice(watchPi, 114, DeviceInUseByMe)
+
+	tests := []struct {
+		name      string
+		prev, cur *snapshot
+		want      []eventSummary
+	}{
+		{
+			name: "nothing changed",
+			prev: newSnapshot([]HubRef{watchPi}, pi114),
+			cur:  newSnapshot([]HubRef{watchPi}, pi114),
+			want: []eventSummary{},
+		},
+		{
+			name: "hub connected with its devices",
+			prev: newSnapshot([]HubRef{watchPi}, pi114),
+			cur:  newSnapshot([]HubRef{watchPi, watchLab}, pi114, lab1),
+			want: []eventSummary{
+				{Type: HubConnected, Hub: "Lab Hub"},
+				{Type: DeviceArrived, Hub: "Lab Hub", Device: "lab.1"},
+			},
+		},
+		{
+			name: "hub disconnected with its devices",
+			prev: newSnapshot([]HubRef{watchPi, watchLab}, pi114, lab1),
+			cur:  newSnapshot([]HubRef{watchPi}, pi114),
+			want: []eventSummary{
+				{Type: DeviceRemoved, Hub: "Lab Hub", Device: "lab.1"},
+				{Type: HubDisconnected, Hub: "Lab Hub"},
+			},
+		},
+		{
+			name: "device changes",
+			prev: newSnapshot([]HubRef{watchPi}, pi114),
+			cur:  newSnapshot([]HubRef{watchPi}, pi114Mine, pi115),
+			want: []eventSummary{
+				{Type: DeviceClaimedByMe, Hub: "Raspberry Hub", Device: "raspberrypi.114", Previous: "raspberrypi.114"},
+				{Type: DeviceArrived, Hub: "Raspberry Hub", Device: "raspberrypi.115"},
+			},
+		},
+		{
+			// Hubs connect first and disconnect last, removals come before
+			// arrivals and changes, and each group is in key order
+			name: "order",
+			prev: newSnapshot([]HubRef{watchPi}, pi114, pi115),
+			cur:  newSnapshot([]HubRef{watchLab}, lab1),
+			want: []eventSummary{
+				{Type: HubConnected, Hub: "Lab Hub"},
+				{Type: DeviceRemoved, Hub: "Raspberry Hub", Device: "raspberrypi.114"},
+				{Type: DeviceRemoved, Hub: "Raspberry Hub", Device: "raspberrypi.115"},
+				{Type: DeviceArrived, Hub: "Lab Hub", Device: "lab.1"},
+				{Type: HubDisconnected, Hub: "Raspberry Hub"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := diffSnapshots(tt.prev, tt.cur, now)
+			if got := summarize(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffSnapshots() = %+v, want %+v", got, tt.want)
+			}
+			for _, event := range events {
+				if !event.Time.Equal(now) {
+					t.Errorf("%s event time = %v, want %v", event.Type, event.Time, now)
+				}
+			}
+		})
+	}
+}
+
+// watchState returns GET CLIENT STATE output listing the devices of the
+// Raspberry Hub, each given as the attributes of its device element
+func watchState(devices ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?><state><server>`)
+	b.WriteString(`<connection serverName="Raspberry Hub" hostname="raspberrypi" serverSerial="b827eb1a2b3c" host="raspberrypi" port="7575" state="2"/>`)
+	for _, device := range devices {
+		b.WriteString("<device " + device + ` autoUse="not-set"/>`)
+	}
+	b.WriteString("</server></state>")
+	return b.String()
+}
+
+// sequenceTransport answers the nth GET CLIENT STATE with the nth state,
+// repeating the last one once they are used up
+func sequenceTransport(states ...string) Transport {
+	var mu sync.Mutex
+	polls := 0
+	return TransportFunc(func(ctx context.Context, command string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		state := states[min(polls, len(states)-1)]
+		polls++
+		return state, nil
+	})
+}
+
+// collect returns the events of a watch until none arrive for a while
+func collect(t *testing.T, events <-chan Event) []Event {
+	t.Helper()
+	var got []Event
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return got
+			}
+			if event.Type == EventError {
+				t.Fatalf("watch failed: %v", event.Err)
+			}
+			got = append(got, event)
+		case <-time.After(200 * time.Millisecond):
+			return got
+		}
+	}
+}
+
+func TestWatchEmitInitial(t *testing.T) {
+	client, err := NewClientWithTransport(sequenceTransport(watchState(`address="115" state="1"`, `address="114" state="3"`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.Watch(ctx, WatchOptions{Interval: time.Hour, EmitInitial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []eventSummary{
+		{Type: HubConnected, Hub: "Raspberry Hub"},
+		{Type: DeviceArrived, Hub: "Raspberry Hub", Device: "raspberrypi.114"},
+		{Type: DeviceArrived, Hub: "Raspberry Hub", Device: "raspberrypi.115"},
+	}
+	if got := summarize(collect(t, events)); !reflect.DeepEqual(got, want) {
+		t.Errorf("initial events = %+v, want %+v", got, want)
+	}
+}
+
+func TestWatchCoalesce(t *testing.T) {
+	// A device appears and is gone again by the next poll, then another is
+	// renamed for good
+	states := []string{
+		watchState(`address="114" state="1"`),
+		watchState(`address="114" state="1"`, `address="115" state="1"`),
+		watchState(`address="114" state="1"`),
+		watchState(`address="114" state="1" nickname="jtag-1"`),
+	}
+	renamed := eventSummary{Type: NicknameChanged, Hub: "Raspberry Hub", Device: "raspberrypi.114", Previous: "raspberrypi.114"}
+
+	tests := []struct {
+		name     string
+		coalesce time.Duration
+		want     []eventSummary
+	}{
+		{"without coalescing", 0, []eventSummary{
+			{Type: DeviceArrived, Hub: "Raspberry Hub", Device: "raspberrypi.115"},
+			{Type: DeviceRemoved, Hub: "Raspberry Hub", Device: "raspberrypi.115"},
+			renamed,
+		}},
+		{"undone within the window", 5 * time.Millisecond, []eventSummary{renamed}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClientWithTransport(sequenceTransport(states...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events, err := client.Watch(ctx, WatchOptions{Interval: time.Millisecond, Coalesce: tt.coalesce})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := summarize(collect(t, events)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}